@@ -12,7 +12,7 @@ require (
 	github.com/whiteducksoftware/golang-utilities/azure/auth v0.1.0-alpha3
 	github.com/whiteducksoftware/golang-utilities/azure/resources v0.1.0-alpha5
 	github.com/whiteducksoftware/golang-utilities/github/actions v0.1.0-alpha6
-
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -14,7 +14,8 @@ import (
 	"github.com/sirupsen/logrus"
 	"github.com/whiteducksoftware/azure-arm-action/pkg/github"
 	"github.com/whiteducksoftware/azure-arm-action/pkg/github/actions"
-	"github.com/whiteducksoftware/golang-utilities/github/actions/io"
+	"github.com/whiteducksoftware/azure-arm-action/pkg/github/io"
+	"github.com/whiteducksoftware/azure-arm-action/pkg/lint"
 )
 
 func init() {
@@ -49,6 +50,22 @@ func main() {
 		logrus.Infof("==== Running workflow %s for %s@%s ====", opts.Workflow, opts.Ref, opts.Commit)
 	}
 
+	// lint the template offline instead of deploying it
+	if opts.Lint {
+		findings, err := actions.Lint(opts)
+		if err != nil {
+			logrus.Errorf("Failed to lint the template: %s", err.Error())
+			io.WriteError(io.Message{Message: fmt.Sprintf("Failed to lint the template: %s", err.Error())})
+			os.Exit(1)
+		}
+
+		writeFindings(opts, findings)
+		if actions.CountFindings(findings, lint.SeverityError) > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+
 	// authenticate
 	authorizer, err := actions.Authenticate(opts)
 	if err != nil {
@@ -57,26 +74,61 @@ func main() {
 		os.Exit(1)
 	}
 
+	// publish the template as template spec version instead of deploying it
+	if opts.PublishTemplateSpec {
+		templateSpecVersionID, err := actions.PublishTemplateSpec(ctx, opts, authorizer)
+		if err != nil {
+			logrus.Errorf("Failed to publish the template spec: %s", err.Error())
+			io.WriteError(io.Message{Message: fmt.Sprintf("Failed to publish the template spec: %s", err.Error())})
+			os.Exit(1)
+		}
+
+		setOutput("templateSpecVersionId", templateSpecVersionID)
+		if opts.RunningAsAction {
+			logrus.Info("==== Successfully finished running the workflow ====")
+		}
+		return
+	}
+
 	// deploy the template
 	resultDeployment, err := actions.Deploy(ctx, opts, authorizer)
 	if err != nil {
 		logrus.Errorf("Failed to deploy the template: %s", err.Error())
-		io.WriteError(io.Message{Message: fmt.Sprintf("Failed to deploy the template: %s", err.Error())})
+		writeError(opts, "Failed to deploy the template", err)
 		os.Exit(1)
 	}
 
-	// parse the template outputs
-	outputs, err := actions.ParseOutputs(resultDeployment.Properties.Outputs)
-	if err != nil {
-		logrus.Errorf("Failed to parse the template outputs: %s", err.Error())
-		io.WriteError(io.Message{Message: fmt.Sprintf("Failed to parse the template outputs: %s", err.Error())})
-		os.Exit(1)
-	}
+	// write the deploymentName to our outputs
+	setOutput("deploymentName", *resultDeployment.Name)
+
+	// a what-if only run has not created the deployment, so there are no template outputs
+	if resultDeployment.Properties != nil {
+		// parse the template outputs
+		outputs, err := actions.ParseOutputs(resultDeployment.Properties.Outputs)
+		if err != nil {
+			logrus.Errorf("Failed to parse the template outputs: %s", err.Error())
+			io.WriteError(io.Message{Message: fmt.Sprintf("Failed to parse the template outputs: %s", err.Error())})
+			os.Exit(1)
+		}
+
+		if opts.FlattenOutputs {
+			outputs = actions.FlattenOutputs(outputs)
+		}
 
-	// write the outputs and the deploymentName to our outputs
-	io.SetOutput("deploymentName", *resultDeployment.Name)
-	for name, output := range outputs {
-		io.SetOutput(name, output.Value)
+		// write the outputs to our outputs
+		for name, output := range outputs {
+			if output.IsSecure() {
+				actions.MaskValue(output.Value)
+			}
+			setOutput(name, output.String())
+		}
+
+		// export the outputs to the environment and files
+		if err := actions.ExportOutputs(opts, outputs); err != nil {
+			logrus.Errorf("Failed to export the template outputs: %s", err.Error())
+			io.WriteError(io.Message{Message: fmt.Sprintf("Failed to export the template outputs: %s", err.Error())})
+			os.Exit(1)
+		}
 	}
 
 	if opts.RunningAsAction {
@@ -84,6 +136,37 @@ func main() {
 	}
 }
 
+// setOutput writes the output of the step, the step fails if it can't be written
+func setOutput(name, value string) {
+	if err := io.SetOutput(name, value); err != nil {
+		logrus.Errorf("Failed to write the output %s: %s", name, err.Error())
+		io.WriteError(io.Message{Message: fmt.Sprintf("Failed to write the output %s: %s", name, err.Error())})
+		os.Exit(1)
+	}
+}
+
+// writeError writes the error as annotations, see actions.ErrorAnnotations
+func writeError(opts github.Options, message string, err error) {
+	for _, annotation := range actions.ErrorAnnotations(opts, message, err) {
+		io.WriteError(annotation)
+	}
+}
+
+// writeFindings writes the lint findings as annotations of their severity
+func writeFindings(opts github.Options, findings []lint.Finding) {
+	for _, finding := range findings {
+		annotation := actions.FindingAnnotation(opts, finding)
+		switch finding.Severity {
+		case lint.SeverityError:
+			io.WriteError(annotation)
+		case lint.SeverityWarning:
+			io.WriteWarning(annotation)
+		default:
+			io.WriteNotice(annotation)
+		}
+	}
+}
+
 func setupInterruptHandler(cancel func()) {
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt)
@@ -0,0 +1,65 @@
+/*
+Copyright (c) 2020 white duck Gesellschaft für Softwareentwicklung mbH
+
+This code is licensed under MIT license (see LICENSE for details)
+*/
+package actions
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+
+	"github.com/whiteducksoftware/azure-arm-action/pkg/github"
+	"github.com/whiteducksoftware/azure-arm-action/pkg/github/io"
+	"github.com/whiteducksoftware/azure-arm-action/pkg/lint"
+)
+
+// ErrorAnnotations builds the annotations of the error, structured deployment errors get one annotation
+// per root cause, which points to the template line if arm reported a json path or position
+func ErrorAnnotations(options github.Options, title string, err error) []io.Message {
+	var deploymentErr *DeploymentError
+	if !errors.As(err, &deploymentErr) {
+		return []io.Message{{Message: fmt.Sprintf("%s: %s", title, err.Error())}}
+	}
+
+	var annotations []io.Message
+	for _, cause := range deploymentErr.RootCauses() {
+		annotation := io.Message{Title: title, Message: cause.String()}
+		if sourceMap := options.TemplateSourceMap; sourceMap != nil {
+			if line, ok := sourceMap.Locate(cause.Target, cause.Message); ok {
+				annotation.File = annotationFile(options, sourceMap.File)
+				annotation.Line = strconv.Itoa(line)
+			}
+		}
+
+		annotations = append(annotations, annotation)
+	}
+
+	return annotations
+}
+
+// FindingAnnotation builds the annotation of the lint finding, which points to the template line
+func FindingAnnotation(options github.Options, finding lint.Finding) io.Message {
+	annotation := io.Message{Title: finding.Rule, Message: finding.Message}
+	if sourceMap := options.TemplateSourceMap; sourceMap != nil {
+		if line, ok := sourceMap.Line(finding.Path); ok {
+			annotation.File = annotationFile(options, sourceMap.File)
+			annotation.Line = strconv.Itoa(line)
+		}
+	}
+
+	return annotation
+}
+
+// annotationFile returns the path relative to the repository, as github expects it for annotations
+func annotationFile(options github.Options, file string) string {
+	if filepath.IsAbs(file) && len(options.Workspace) > 0 {
+		if relative, err := filepath.Rel(options.Workspace, file); err == nil {
+			file = relative
+		}
+	}
+
+	return filepath.ToSlash(file)
+}
@@ -0,0 +1,97 @@
+package actions
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/whiteducksoftware/azure-arm-action/pkg/github"
+	"github.com/whiteducksoftware/azure-arm-action/pkg/util"
+)
+
+const annotatedTemplate = `{
+  "$schema": "https://schema.management.azure.com/schemas/2019-04-01/deploymentTemplate.json#",
+  "contentVersion": "1.0.0.0",
+  "resources": [
+    {
+      "type": "Microsoft.Storage/storageAccounts",
+      "apiVersion": "2021-04-01",
+      "name": "storage",
+      "sku": {
+        "name": "Premium_XYZ"
+      }
+    }
+  ]
+}`
+
+func TestValidationErrorAnnotations(t *testing.T) {
+	workspace := t.TempDir()
+	path := filepath.Join(workspace, "templates", "template.json")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("Got unexpected error: %s", err)
+	}
+	if err := ioutil.WriteFile(path, []byte(annotatedTemplate), 0644); err != nil {
+		t.Fatalf("Got unexpected error: %s", err)
+	}
+
+	template, sourceMap, err := util.ReadJSONWithSourceMap(path)
+	if err != nil {
+		t.Fatalf("Got unexpected error: %s", err)
+	}
+
+	// arm reports the position in the compact template it received
+	compact, _ := json.Marshal(template)
+	column := strings.Index(string(compact), `"sku"`) + len(`"sku":`)
+
+	stand := newARMStandIn(t)
+	stand.respond = func(request armRequest) (int, interface{}) {
+		if !strings.HasSuffix(request.Path, "/validate") {
+			return 0, nil
+		}
+
+		return http.StatusBadRequest, map[string]interface{}{
+			"error": map[string]interface{}{
+				"code":    "InvalidTemplateDeployment",
+				"message": "The template deployment 'test' is not valid according to the validation procedure.",
+				"details": []interface{}{
+					map[string]interface{}{
+						"code":    "InvalidTemplate",
+						"message": fmt.Sprintf("Deployment template validation failed: 'The sku is not supported at line '1' and column '%d'.'", column),
+					},
+				},
+			},
+		}
+	}
+
+	options := testOptions(stand.URL)
+	options.Scope = github.ScopeResourceGroup
+	options.ResourceGroupName = "my-group"
+	options.Workspace = workspace
+	options.Template = template
+	options.TemplateSourceMap = &sourceMap
+
+	_, err = Deploy(context.Background(), options, autorest.NullAuthorizer{})
+	if err == nil {
+		t.Fatal("Got no error for a rejected validation")
+	}
+
+	annotations := ErrorAnnotations(options, "Failed to deploy the template", err)
+	if len(annotations) != 1 {
+		t.Fatalf("Got invalid count of annotations, expected 1 got %d: %v", len(annotations), annotations)
+	}
+
+	annotation := annotations[0]
+	if annotation.File != "templates/template.json" || annotation.Line != "9" {
+		t.Errorf("Got invalid location, expected templates/template.json:9 got %s:%s", annotation.File, annotation.Line)
+	}
+	if annotation.Title != "Failed to deploy the template" || !strings.HasPrefix(annotation.Message, "InvalidTemplate: Deployment template validation failed") {
+		t.Errorf("Got invalid annotation %v", annotation)
+	}
+}
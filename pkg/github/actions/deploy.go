@@ -9,10 +9,12 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/Azure/azure-sdk-for-go/profiles/latest/resources/mgmt/resources"
 	"github.com/Azure/go-autorest/autorest"
-	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
 	"github.com/whiteducksoftware/azure-arm-action/pkg/github"
 	"github.com/whiteducksoftware/azure-arm-action/pkg/util"
@@ -21,60 +23,129 @@ import (
 
 // Deploy takes our inputs and initaite and
 // waits for completion of the arm template deployment
-func Deploy(ctx context.Context, options github.Options, authorizer autorest.Authorizer) (resources.DeploymentExtended, error) {
-	var err error
-
+func Deploy(ctx context.Context, options github.Options, authorizer autorest.Authorizer) (result resources.DeploymentExtended, err error) {
 	// Load the arm deployments client
 	deploymentsClient := deployments.GetClientWithBaseUri(options.Credentials.ARMEndpointURL, options.Credentials.SubscriptionID, authorizer)
-	u := uuid.New().String()
-	deploymentName := fmt.Sprintf("%s-%s", options.DeploymentName, u)
-	logrus.Infof("Creating deployment %s, mode: %s", deploymentName, options.DeploymentMode)
+	operationsClient := resources.NewDeploymentOperationsClientWithBaseURI(options.Credentials.ARMEndpointURL, options.Credentials.SubscriptionID)
+	operationsClient.Authorizer = authorizer
+	deploymentName, err := DeploymentName(options)
+	if err != nil {
+		return resources.DeploymentExtended{}, err
+	}
+	logrus.Infof("Creating deployment %s, scope: %s, mode: %s", deploymentName, options.Scope, options.DeploymentMode)
 
-	// Build our final parameters
-	parameter := util.MergeParameters(options.Parameters, options.OverrideParameters)
+	// Report the deployment in the job summary, no matter how it ends
+	start := time.Now()
+	defer func() {
+		writeSummary(ctx, operationsClient, options, deploymentName, start, result, err)
+	}()
 
-	// Validate deployment
-	logrus.Infof("Validating deployment %s", deploymentName)
+	// Build our final parameters, every layer replaces or deep merges the previous ones
+	parameter, provenance := util.MergeParameterLayers(options.ParameterLayers()...)
+	logParameterProvenance(provenance)
+
+	// Key vault references are passed by the vault name, arm needs the resource id
+	if err := resolveKeyVaultReferences(ctx, options, authorizer, parameter); err != nil {
+		return resources.DeploymentExtended{}, err
+	}
+
+	// Secure parameters must not show up in the log, e.g. in debug output or arm errors
+	maskSecureParameters(options.Template, parameter)
+
+	// Build the deployment, the template and parameters are passed inline or linked
+	properties, err := deploymentProperties(options, parameter)
+	if err != nil {
+		return resources.DeploymentExtended{}, err
+	}
+
+	// Check the parameters locally, arm takes a while to report the same.
+	// Linked parameters are only known to arm, so they can't be checked.
+	if options.ValidateParameters && properties.ParametersLink == nil {
+		if err := validateParameters(options.Template, parameter, provenance); err != nil {
+			return resources.DeploymentExtended{}, err
+		}
+	}
+
+	// Skip the deployment if the template and parameters are unchanged since the last successful one
+	hash, hashable := deploymentHash(options, properties)
+	willCreate := !options.WhatIf || options.DeployAfterWhatIf
+	if options.SkipUnchanged && willCreate {
+		if !hashable {
+			logrus.Info("Linked templates and parameters can't be compared, skipUnchanged is ignored.")
+		} else {
+			unchanged, err := unchangedDeployment(ctx, deploymentsClient, options, hash)
+			if err != nil {
+				return resources.DeploymentExtended{}, err
+			}
 
-	var validationResult resources.DeploymentValidateResult
-	if len(options.ResourceGroupName) > 0 {
-		validationResult, err = deployments.Validate(ctx, deploymentsClient, options.ResourceGroupName, deploymentName, options.DeploymentMode, options.Template, parameter)
-	} else if len(options.ManagementGroupId) > 0 {
-		validationResult, err = deployments.ValidateAtManagementGroupScope(ctx, deploymentsClient, deploymentName, options.DeploymentName, options.DeploymentMode, options.Template, parameter)
-	} else {
-		validationResult, err = deployments.ValidateAtSubscriptionScope(ctx, deploymentsClient, deploymentName, options.DeploymentMode, options.Template, parameter)
+			if unchanged != nil {
+				logrus.Infof("Template and parameters are unchanged since deployment %s, skipping the deployment.", *unchanged.Name)
+				return *unchanged, nil
+			}
+		}
 	}
 
+	// Validate deployment
+	logrus.Infof("Validating deployment %s", deploymentName)
+	validationResult, err := validate(ctx, deploymentsClient, options, deploymentName, properties)
 	if err != nil {
 		return resources.DeploymentExtended{}, err
 	}
 
 	if validationResult.StatusCode != http.StatusOK {
-		return resources.DeploymentExtended{}, fmt.Errorf("%s, %s", validationResult.Status, *validationResult.Error.Message)
+		return resources.DeploymentExtended{}, newDeploymentError(fmt.Sprintf("validation of deployment %s failed: %s", deploymentName, validationResult.Status), validationResult.Error)
 	}
 	logrus.Info("Validation finished.")
 
+	// Preview the changes of the deployment
+	if options.WhatIf {
+		logrus.Infof("Running what-if for deployment %s", deploymentName)
+		whatIfResult, err := WhatIf(ctx, deploymentsClient, options, deploymentName, properties)
+		if err != nil {
+			return resources.DeploymentExtended{}, err
+		}
+		printWhatIfResult(whatIfResult)
+		logrus.Info("What-if finished.")
+
+		// A what-if only run must not create the deployment
+		if !options.DeployAfterWhatIf {
+			return resources.DeploymentExtended{Name: &deploymentName}, nil
+		}
+	}
+
 	// Create and wait for completion of the deployment
 	logrus.Infof("Creating deployment %s", deploymentName)
 
-	var resultDeployment resources.DeploymentExtended
-	if len(options.ResourceGroupName) > 0 {
-		resultDeployment, err = deployments.Create(ctx, deploymentsClient, options.ResourceGroupName, deploymentName, options.DeploymentMode, options.Template, parameter)
-	} else if len(options.ManagementGroupId) > 0 {
-		resultDeployment, err = deployments.CreateAtManagementGroupScope(ctx, deploymentsClient, deploymentName, options.DeploymentName, options.DeploymentMode, options.Template, parameter)
-	} else {
-		resultDeployment, err = deployments.CreateAtSubscriptionScope(ctx, deploymentsClient, deploymentName, options.DeploymentMode, options.Template, parameter)
+	stopProgress := watchProgress(ctx, operationsClient, options, deploymentName)
+	resultDeployment, err := create(ctx, deploymentsClient, options, deploymentName, properties, deploymentTags(options, hash))
+	stopProgress()
+	if err == nil && resultDeployment.StatusCode != http.StatusOK {
+		err = fmt.Errorf("%s", resultDeployment.Status)
 	}
 
 	if err != nil {
-		return resources.DeploymentExtended{}, err
-	}
+		err = failedDeploymentError(ctx, deploymentsClient, operationsClient, options, deploymentName, err)
 
-	// verify the status
-	if resultDeployment.StatusCode != http.StatusOK {
-		return resources.DeploymentExtended{}, fmt.Errorf("%s", resultDeployment.Status)
+		// arm rolls back to the on error deployment, report how that went
+		if properties.OnErrorDeployment != nil {
+			err = withRollback(ctx, deploymentsClient, options, deploymentName, err)
+		}
+		return resources.DeploymentExtended{}, err
 	}
 	logrus.Info("Template deployment finished.")
 
 	return resultDeployment, nil
 }
+
+// logParameterProvenance logs which layers supplied the final value of each parameter, the values aren't logged
+func logParameterProvenance(provenance map[string][]string) {
+	names := make([]string, 0, len(provenance))
+	for name := range provenance {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		logrus.Infof("Parameter %s from %s", name, strings.Join(provenance[name], " + "))
+	}
+}
@@ -0,0 +1,300 @@
+package actions
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/whiteducksoftware/azure-arm-action/pkg/github"
+	"github.com/whiteducksoftware/golang-utilities/azure/auth"
+)
+
+const testSubscriptionID = "00000000-0000-0000-0000-000000000000"
+
+// armRequest is a request which has been received by the arm stand-in
+type armRequest struct {
+	Method string
+	Path   string
+	Body   map[string]interface{}
+}
+
+// armStandIn is a local http server which answers like arm and records all requests
+type armStandIn struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests []armRequest
+
+	// respond overrides the answer to a request, the default answer is used if it returns 0
+	respond func(request armRequest) (int, interface{})
+}
+
+func newARMStandIn(t *testing.T) *armStandIn {
+	stand := &armStandIn{}
+	stand.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request := armRequest{Method: r.Method, Path: r.URL.Path}
+		if data, err := ioutil.ReadAll(r.Body); err == nil && len(data) > 0 {
+			if err := json.Unmarshal(data, &request.Body); err != nil {
+				t.Errorf("Got invalid request body for %s %s: %s", r.Method, r.URL.Path, err)
+			}
+		}
+
+		stand.mu.Lock()
+		stand.requests = append(stand.requests, request)
+		respond := stand.respond
+		stand.mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		if respond != nil {
+			if status, body := respond(request); status != 0 {
+				w.WriteHeader(status)
+				json.NewEncoder(w).Encode(body)
+				return
+			}
+		}
+
+		name := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":   strings.TrimSuffix(r.URL.Path, "/validate"),
+			"name": name,
+			"properties": map[string]interface{}{
+				"provisioningState": "Succeeded",
+				"outputs":           map[string]interface{}{},
+			},
+		})
+	}))
+	t.Cleanup(stand.Close)
+
+	return stand
+}
+
+// writes returns all validate and create requests
+func (stand *armStandIn) writes() []armRequest {
+	stand.mu.Lock()
+	defer stand.mu.Unlock()
+
+	var writes []armRequest
+	for _, request := range stand.requests {
+		if request.Method != http.MethodGet {
+			writes = append(writes, request)
+		}
+	}
+
+	return writes
+}
+
+func testOptions(endpoint string) github.Options {
+	return github.Options{
+		Inputs: github.Inputs{
+			Credentials: &auth.SDKAuth{
+				ARMEndpointURL: endpoint,
+				SubscriptionID: testSubscriptionID,
+			},
+			Template: map[string]interface{}{
+				"$schema":        "https://schema.management.azure.com/schemas/2019-04-01/deploymentTemplate.json#",
+				"contentVersion": "1.0.0.0",
+				"resources":      []interface{}{},
+			},
+			DeploymentName: "test",
+			DeploymentMode: "Incremental",
+		},
+	}
+}
+
+func TestDeployRequestShape(t *testing.T) {
+	tests := []struct {
+		name         string
+		scope        github.Scope
+		resourceGrp  string
+		mgmtGroup    string
+		location     string
+		pathPrefix   string
+		wantLocation bool
+	}{
+		{
+			name:        "resource group",
+			scope:       github.ScopeResourceGroup,
+			resourceGrp: "my-group",
+			location:    "westeurope",
+			pathPrefix:  "/subscriptions/" + testSubscriptionID + "/resourcegroups/my-group/providers/Microsoft.Resources/deployments/test-",
+		},
+		{
+			name:         "subscription",
+			scope:        github.ScopeSubscription,
+			location:     "westeurope",
+			pathPrefix:   "/subscriptions/" + testSubscriptionID + "/providers/Microsoft.Resources/deployments/test-",
+			wantLocation: true,
+		},
+		{
+			name:         "management group",
+			scope:        github.ScopeManagementGroup,
+			mgmtGroup:    "my-management-group",
+			location:     "westeurope",
+			pathPrefix:   "/providers/Microsoft.Management/managementGroups/my-management-group/providers/Microsoft.Resources/deployments/test-",
+			wantLocation: true,
+		},
+		{
+			name:         "tenant",
+			scope:        github.ScopeTenant,
+			location:     "westeurope",
+			pathPrefix:   "/providers/Microsoft.Resources/deployments/test-",
+			wantLocation: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stand := newARMStandIn(t)
+			options := testOptions(stand.URL)
+			options.Scope = test.scope
+			options.ResourceGroupName = test.resourceGrp
+			options.ManagementGroupId = test.mgmtGroup
+			options.Location = test.location
+
+			result, err := Deploy(context.Background(), options, autorest.NullAuthorizer{})
+			if err != nil {
+				t.Fatalf("Deploy failed: %s", err)
+			}
+
+			writes := stand.writes()
+			if len(writes) != 2 {
+				t.Fatalf("Got invalid count of requests, expected 2 (validate, create) got %d", len(writes))
+			}
+
+			validateRequest, createRequest := writes[0], writes[1]
+			if validateRequest.Method != http.MethodPost || !strings.HasPrefix(validateRequest.Path, test.pathPrefix) || !strings.HasSuffix(validateRequest.Path, "/validate") {
+				t.Errorf("Got invalid validate request %s %s, expected POST %s.../validate", validateRequest.Method, validateRequest.Path, test.pathPrefix)
+			}
+			if createRequest.Method != http.MethodPut || !strings.HasPrefix(createRequest.Path, test.pathPrefix) {
+				t.Errorf("Got invalid create request %s %s, expected PUT %s...", createRequest.Method, createRequest.Path, test.pathPrefix)
+			}
+
+			// The generated name has to be the same for both requests and the result
+			if createRequest.Path+"/validate" != validateRequest.Path {
+				t.Errorf("Got different deployments for validate (%s) and create (%s)", validateRequest.Path, createRequest.Path)
+			}
+			if result.Name == nil || !strings.HasSuffix(createRequest.Path, "/"+*result.Name) {
+				t.Errorf("Got invalid deployment name in the result, expected the name of %s", createRequest.Path)
+			}
+
+			for _, request := range writes {
+				location, hasLocation := request.Body["location"]
+				if test.wantLocation && location != test.location {
+					t.Errorf("Got invalid location for %s %s, expected %s got %v", request.Method, request.Path, test.location, location)
+				}
+				if !test.wantLocation && hasLocation {
+					t.Errorf("Got unexpected location for %s %s: %v", request.Method, request.Path, location)
+				}
+
+				properties, _ := request.Body["properties"].(map[string]interface{})
+				if properties["mode"] != "Incremental" {
+					t.Errorf("Got invalid mode for %s %s, expected Incremental got %v", request.Method, request.Path, properties["mode"])
+				}
+				if _, ok := properties["template"]; !ok {
+					t.Errorf("Template is missing in %s %s", request.Method, request.Path)
+				}
+			}
+		})
+	}
+}
+
+func TestDeployLinkedParameters(t *testing.T) {
+	stand := newARMStandIn(t)
+	options := testOptions(stand.URL)
+	options.Scope = github.ScopeResourceGroup
+	options.ResourceGroupName = "my-group"
+	options.ValidateParameters = true
+	options.Template["parameters"] = map[string]interface{}{
+		"name": map[string]interface{}{"type": "string"},
+	}
+	options.ParametersLink = "https://example.com/parameters.json"
+
+	// The required parameter is only set by the linked parameters, which are unknown locally
+	if _, err := Deploy(context.Background(), options, autorest.NullAuthorizer{}); err != nil {
+		t.Fatalf("Deploy failed: %s", err)
+	}
+
+	writes := stand.writes()
+	if len(writes) != 2 {
+		t.Fatalf("Got invalid count of requests, expected 2 (validate, create) got %d", len(writes))
+	}
+
+	properties, _ := writes[1].Body["properties"].(map[string]interface{})
+	parametersLink, _ := properties["parametersLink"].(map[string]interface{})
+	if parametersLink["uri"] != "https://example.com/parameters.json" {
+		t.Errorf("Got invalid parametersLink, expected https://example.com/parameters.json got %v", properties["parametersLink"])
+	}
+}
+
+// invalidTemplateResponse is the error arm answers a validation of an invalid template with
+var invalidTemplateResponse = map[string]interface{}{
+	"error": map[string]interface{}{
+		"code":    "InvalidTemplateDeployment",
+		"message": "The template deployment 'test' is not valid according to the validation procedure.",
+		"details": []interface{}{
+			map[string]interface{}{
+				"code":    "InvalidTemplate",
+				"message": "Deployment template validation failed: 'The template resource 'resources[0].sku' is not valid.'",
+				"details": []interface{}{
+					map[string]interface{}{"code": "InvalidSku", "message": "The sku Premium_XYZ is not supported.", "target": "resources[0].sku"},
+				},
+			},
+		},
+	},
+}
+
+func TestDeployValidationError(t *testing.T) {
+	tests := []struct {
+		name    string
+		failing string
+		whatIf  bool
+	}{
+		{name: "validate", failing: "/validate"},
+		{name: "what-if", failing: "/whatIf", whatIf: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stand := newARMStandIn(t)
+			stand.respond = func(request armRequest) (int, interface{}) {
+				if strings.HasSuffix(request.Path, test.failing) {
+					return http.StatusBadRequest, invalidTemplateResponse
+				}
+				return 0, nil
+			}
+
+			options := testOptions(stand.URL)
+			options.Scope = github.ScopeResourceGroup
+			options.ResourceGroupName = "my-group"
+			options.WhatIf = test.whatIf
+
+			_, err := Deploy(context.Background(), options, autorest.NullAuthorizer{})
+			var deploymentErr *DeploymentError
+			if !errors.As(err, &deploymentErr) {
+				t.Fatalf("Got no deployment error: %v", err)
+			}
+
+			causes := deploymentErr.RootCauses()
+			want := "InvalidSku: The sku Premium_XYZ is not supported. (target: resources[0].sku)"
+			if len(causes) != 1 || causes[0].String() != want {
+				t.Errorf("Got invalid root causes, expected %s got %v", want, causes)
+			}
+			if !strings.Contains(err.Error(), "\n    - InvalidTemplate: Deployment template validation failed") {
+				t.Errorf("Details are missing in the error:\n%s", err)
+			}
+
+			for _, request := range stand.writes() {
+				if request.Method == http.MethodPut {
+					t.Errorf("Got create request %s after a failed validation", request.Path)
+				}
+			}
+		})
+	}
+}
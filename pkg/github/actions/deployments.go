@@ -0,0 +1,216 @@
+/*
+Copyright (c) 2020 white duck Gesellschaft für Softwareentwicklung mbH
+
+This code is licensed under MIT license (see LICENSE for details)
+*/
+package actions
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/profiles/latest/resources/mgmt/resources"
+	"github.com/whiteducksoftware/azure-arm-action/pkg/github"
+)
+
+// deploymentProperties builds the properties of the deployment, the template
+// and parameters are either passed inline, linked if they are remote files or
+// the template is referenced from a template spec
+func deploymentProperties(options github.Options, parameter map[string]interface{}) (*resources.DeploymentProperties, error) {
+	properties := &resources.DeploymentProperties{
+		Mode:              resources.DeploymentMode(options.DeploymentMode),
+		OnErrorDeployment: onErrorDeployment(options),
+	}
+
+	if len(options.TemplateSpecID) > 0 {
+		spec, err := ParseTemplateSpecID(options)
+		if err != nil {
+			return nil, err
+		}
+
+		properties.TemplateLink = &resources.TemplateLink{
+			ID: stringPtr(spec.ID()),
+		}
+	} else if len(options.TemplateLink) > 0 {
+		templateLink := &resources.TemplateLink{
+			URI: stringPtr(string(options.TemplateLink)),
+		}
+		if len(options.SASToken) > 0 {
+			templateLink.QueryString = stringPtr(strings.TrimPrefix(options.SASToken, "?"))
+		}
+		properties.TemplateLink = templateLink
+	} else {
+		properties.Template = options.Template
+	}
+
+	if len(options.ParametersLink) > 0 {
+		if options.HasLocalParameters() {
+			return nil, fmt.Errorf("overrideParameters and parameterSources can not be combined with a linked parameters file")
+		}
+
+		properties.ParametersLink = &resources.ParametersLink{
+			URI: stringPtr(withQueryString(string(options.ParametersLink), options.SASToken)),
+		}
+	} else {
+		properties.Parameters = parameter
+	}
+
+	return properties, nil
+}
+
+// validate validates the deployment at the scope selected by the inputs
+func validate(ctx context.Context, client resources.DeploymentsClient, options github.Options, deploymentName string, properties *resources.DeploymentProperties) (resources.DeploymentValidateResult, error) {
+	var err error
+	var result resources.DeploymentValidateResult
+	location := deploymentLocation(options)
+	switch options.Scope {
+	case github.ScopeResourceGroup:
+		var future resources.DeploymentsValidateFuture
+		future, err = client.Validate(ctx, options.ResourceGroupName, deploymentName, resources.Deployment{Properties: properties})
+		if err == nil {
+			err = future.WaitForCompletionRef(ctx, client.Client)
+		}
+		if err == nil {
+			result, err = future.Result(client)
+		}
+	case github.ScopeManagementGroup:
+		var future resources.DeploymentsValidateAtManagementGroupScopeFuture
+		future, err = client.ValidateAtManagementGroupScope(ctx, options.ManagementGroupId, deploymentName, resources.ScopedDeployment{Location: location, Properties: properties})
+		if err == nil {
+			err = future.WaitForCompletionRef(ctx, client.Client)
+		}
+		if err == nil {
+			result, err = future.Result(client)
+		}
+	case github.ScopeTenant:
+		var future resources.DeploymentsValidateAtTenantScopeFuture
+		future, err = client.ValidateAtTenantScope(ctx, deploymentName, resources.ScopedDeployment{Location: location, Properties: properties})
+		if err == nil {
+			err = future.WaitForCompletionRef(ctx, client.Client)
+		}
+		if err == nil {
+			result, err = future.Result(client)
+		}
+	default:
+		var future resources.DeploymentsValidateAtSubscriptionScopeFuture
+		future, err = client.ValidateAtSubscriptionScope(ctx, deploymentName, resources.Deployment{Location: location, Properties: properties})
+		if err == nil {
+			err = future.WaitForCompletionRef(ctx, client.Client)
+		}
+		if err == nil {
+			result, err = future.Result(client)
+		}
+	}
+
+	if err != nil {
+		// arm rejects invalid templates with 400, the error response is part of the autorest error
+		if deploymentErr := newServiceDeploymentError(fmt.Sprintf("validation of deployment %s failed", deploymentName), err); deploymentErr != nil {
+			return resources.DeploymentValidateResult{}, deploymentErr
+		}
+		return resources.DeploymentValidateResult{}, fmt.Errorf("cannot validate deployment: %v", err)
+	}
+
+	return result, nil
+}
+
+// create creates the deployment at the scope selected by the inputs and waits for its completion
+func create(ctx context.Context, client resources.DeploymentsClient, options github.Options, deploymentName string, properties *resources.DeploymentProperties, tags map[string]*string) (resources.DeploymentExtended, error) {
+	var err error
+	var result resources.DeploymentExtended
+	location := deploymentLocation(options)
+	switch options.Scope {
+	case github.ScopeResourceGroup:
+		var future resources.DeploymentsCreateOrUpdateFuture
+		future, err = client.CreateOrUpdate(ctx, options.ResourceGroupName, deploymentName, resources.Deployment{Properties: properties, Tags: tags})
+		if err == nil {
+			err = future.WaitForCompletionRef(ctx, client.Client)
+		}
+		if err == nil {
+			result, err = future.Result(client)
+		}
+	case github.ScopeManagementGroup:
+		var future resources.DeploymentsCreateOrUpdateAtManagementGroupScopeFuture
+		future, err = client.CreateOrUpdateAtManagementGroupScope(ctx, options.ManagementGroupId, deploymentName, resources.ScopedDeployment{Location: location, Properties: properties, Tags: tags})
+		if err == nil {
+			err = future.WaitForCompletionRef(ctx, client.Client)
+		}
+		if err == nil {
+			result, err = future.Result(client)
+		}
+	case github.ScopeTenant:
+		var future resources.DeploymentsCreateOrUpdateAtTenantScopeFuture
+		future, err = client.CreateOrUpdateAtTenantScope(ctx, deploymentName, resources.ScopedDeployment{Location: location, Properties: properties, Tags: tags})
+		if err == nil {
+			err = future.WaitForCompletionRef(ctx, client.Client)
+		}
+		if err == nil {
+			result, err = future.Result(client)
+		}
+	default:
+		var future resources.DeploymentsCreateOrUpdateAtSubscriptionScopeFuture
+		future, err = client.CreateOrUpdateAtSubscriptionScope(ctx, deploymentName, resources.Deployment{Location: location, Properties: properties, Tags: tags})
+		if err == nil {
+			err = future.WaitForCompletionRef(ctx, client.Client)
+		}
+		if err == nil {
+			result, err = future.Result(client)
+		}
+	}
+
+	if err != nil {
+		return resources.DeploymentExtended{}, fmt.Errorf("cannot create deployment: %v", err)
+	}
+
+	return result, nil
+}
+
+// get reads the deployment at the scope selected by the inputs
+func get(ctx context.Context, client resources.DeploymentsClient, options github.Options, deploymentName string) (resources.DeploymentExtended, error) {
+	var err error
+	var result resources.DeploymentExtended
+	switch options.Scope {
+	case github.ScopeResourceGroup:
+		result, err = client.Get(ctx, options.ResourceGroupName, deploymentName)
+	case github.ScopeManagementGroup:
+		result, err = client.GetAtManagementGroupScope(ctx, options.ManagementGroupId, deploymentName)
+	case github.ScopeTenant:
+		result, err = client.GetAtTenantScope(ctx, deploymentName)
+	default:
+		result, err = client.GetAtSubscriptionScope(ctx, deploymentName)
+	}
+
+	if err != nil {
+		return resources.DeploymentExtended{}, fmt.Errorf("cannot get deployment: %v", err)
+	}
+
+	return result, nil
+}
+
+// deploymentLocation returns the location in which the deployment metadata is stored,
+// it's only sent for scopes above the resource group
+func deploymentLocation(options github.Options) *string {
+	if options.Scope == github.ScopeResourceGroup || len(options.Location) == 0 {
+		return nil
+	}
+
+	return stringPtr(options.Location)
+}
+
+// withQueryString appends the query string (e.g. a sas token) to the uri
+func withQueryString(uri, queryString string) string {
+	queryString = strings.TrimPrefix(queryString, "?")
+	if len(queryString) == 0 {
+		return uri
+	}
+
+	if strings.Contains(uri, "?") {
+		return fmt.Sprintf("%s&%s", uri, queryString)
+	}
+
+	return fmt.Sprintf("%s?%s", uri, queryString)
+}
+
+func stringPtr(value string) *string {
+	return &value
+}
@@ -0,0 +1,262 @@
+/*
+Copyright (c) 2020 white duck Gesellschaft für Softwareentwicklung mbH
+
+This code is licensed under MIT license (see LICENSE for details)
+*/
+package actions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/profiles/latest/resources/mgmt/resources"
+	"github.com/Azure/go-autorest/autorest/azure"
+	"github.com/sirupsen/logrus"
+	"github.com/whiteducksoftware/azure-arm-action/pkg/github"
+)
+
+// ErrorDetail is a single arm error with its nested details
+type ErrorDetail struct {
+	Code    string
+	Message string
+	Target  string
+	Details []ErrorDetail
+}
+
+// FailedOperation is a deployment operation which failed
+type FailedOperation struct {
+	ResourceID   string
+	ResourceType string
+	ResourceName string
+	StatusCode   string
+	Error        *ErrorDetail
+}
+
+// RootCause is an error without further details, optionally of a failed resource
+type RootCause struct {
+	ResourceID string
+	Code       string
+	Message    string
+	Target     string
+}
+
+// String formats the root cause as single line
+func (cause RootCause) String() string {
+	var sb strings.Builder
+	if len(cause.ResourceID) > 0 {
+		fmt.Fprintf(&sb, "%s: ", cause.ResourceID)
+	}
+	if len(cause.Code) > 0 {
+		fmt.Fprintf(&sb, "%s: ", cause.Code)
+	}
+	sb.WriteString(cause.Message)
+	if len(cause.Target) > 0 {
+		fmt.Fprintf(&sb, " (target: %s)", cause.Target)
+	}
+
+	return sb.String()
+}
+
+// DeploymentError is the structured error of a failed validation or deployment
+type DeploymentError struct {
+	Summary       string
+	CorrelationID string
+	Errors        []ErrorDetail
+	Operations    []FailedOperation
+}
+
+// Error renders the error and the failed operations as readable tree
+func (e *DeploymentError) Error() string {
+	var sb strings.Builder
+	sb.WriteString(e.Summary)
+	for _, detail := range e.Errors {
+		writeErrorDetail(&sb, detail, 1)
+	}
+
+	if len(e.Operations) > 0 {
+		sb.WriteString("\nFailed operations:")
+		for _, operation := range e.Operations {
+			fmt.Fprintf(&sb, "\n  - %s (%s) %s", operation.ResourceID, operation.ResourceType, operation.StatusCode)
+			if operation.Error != nil {
+				writeErrorDetail(&sb, *operation.Error, 2)
+			}
+		}
+	}
+
+	return sb.String()
+}
+
+// RootCauses returns the innermost errors, the errors of the failed operations are preferred
+// as the deployment error usually only says that at least one operation failed
+func (e *DeploymentError) RootCauses() []RootCause {
+	var causes []RootCause
+	seen := make(map[RootCause]bool)
+	add := func(resourceID string, detail ErrorDetail) {
+		for _, leaf := range leafErrors(detail) {
+			cause := RootCause{ResourceID: resourceID, Code: leaf.Code, Message: leaf.Message, Target: leaf.Target}
+			if !seen[cause] {
+				seen[cause] = true
+				causes = append(causes, cause)
+			}
+		}
+	}
+
+	for _, operation := range e.Operations {
+		if operation.Error != nil {
+			add(operation.ResourceID, *operation.Error)
+		}
+	}
+
+	if len(causes) == 0 {
+		for _, detail := range e.Errors {
+			add("", detail)
+		}
+	}
+
+	if len(causes) == 0 {
+		causes = append(causes, RootCause{Message: e.Summary})
+	}
+
+	return causes
+}
+
+func writeErrorDetail(sb *strings.Builder, detail ErrorDetail, depth int) {
+	fmt.Fprintf(sb, "\n%s- ", strings.Repeat("  ", depth))
+	if len(detail.Code) > 0 {
+		fmt.Fprintf(sb, "%s: ", detail.Code)
+	}
+	sb.WriteString(detail.Message)
+	if len(detail.Target) > 0 {
+		fmt.Fprintf(sb, " (target: %s)", detail.Target)
+	}
+
+	for _, child := range detail.Details {
+		writeErrorDetail(sb, child, depth+1)
+	}
+}
+
+func leafErrors(detail ErrorDetail) []ErrorDetail {
+	if len(detail.Details) == 0 {
+		return []ErrorDetail{detail}
+	}
+
+	var leafs []ErrorDetail
+	for _, child := range detail.Details {
+		leafs = append(leafs, leafErrors(child)...)
+	}
+
+	return leafs
+}
+
+// newErrorDetail converts the arm error response including all nested details
+func newErrorDetail(response *resources.ErrorResponse) *ErrorDetail {
+	if response == nil {
+		return nil
+	}
+
+	detail := &ErrorDetail{
+		Code:    stringValue(response.Code),
+		Message: stringValue(response.Message),
+		Target:  stringValue(response.Target),
+	}
+	if response.Details != nil {
+		for i := range *response.Details {
+			detail.Details = append(detail.Details, *newErrorDetail(&(*response.Details)[i]))
+		}
+	}
+
+	return detail
+}
+
+// newDeploymentError builds the error from the arm error response
+func newDeploymentError(summary string, response *resources.ErrorResponse) *DeploymentError {
+	deploymentErr := &DeploymentError{Summary: summary}
+	if detail := newErrorDetail(response); detail != nil {
+		deploymentErr.Errors = append(deploymentErr.Errors, *detail)
+	}
+
+	return deploymentErr
+}
+
+// newServiceDeploymentError builds the error from the arm error of a rejected request, e.g. a validation which
+// failed with 400. It returns nil if the error doesn't carry an arm error response.
+func newServiceDeploymentError(summary string, err error) *DeploymentError {
+	var serviceErr *azure.ServiceError
+	var requestErr *azure.RequestError
+	switch {
+	case errors.As(err, &requestErr) && requestErr.ServiceError != nil:
+		serviceErr = requestErr.ServiceError
+	case errors.As(err, &serviceErr):
+	default:
+		return nil
+	}
+
+	return &DeploymentError{Summary: summary, Errors: []ErrorDetail{serviceErrorDetail(serviceErr)}}
+}
+
+// serviceErrorDetail converts the service error of autorest, its nested details are plain json objects
+func serviceErrorDetail(serviceErr *azure.ServiceError) ErrorDetail {
+	detail := ErrorDetail{Code: serviceErr.Code, Message: serviceErr.Message, Target: stringValue(serviceErr.Target)}
+	for _, child := range serviceErr.Details {
+		detail.Details = append(detail.Details, jsonErrorDetail(child))
+	}
+
+	return detail
+}
+
+func jsonErrorDetail(object map[string]interface{}) ErrorDetail {
+	detail := ErrorDetail{}
+	detail.Code, _ = object["code"].(string)
+	detail.Message, _ = object["message"].(string)
+	detail.Target, _ = object["target"].(string)
+
+	children, _ := object["details"].([]interface{})
+	for _, child := range children {
+		if child, ok := child.(map[string]interface{}); ok {
+			detail.Details = append(detail.Details, jsonErrorDetail(child))
+		}
+	}
+
+	return detail
+}
+
+// failedDeploymentError reads the error and the failed operations of the deployment,
+// the original error is returned if they can't be read
+func failedDeploymentError(ctx context.Context, client resources.DeploymentsClient, operationsClient resources.DeploymentOperationsClient, options github.Options, deploymentName string, deploymentErr error) error {
+	deployment, err := get(ctx, client, options, deploymentName)
+	if err != nil || deployment.Properties == nil || deployment.Properties.Error == nil {
+		logrus.Debugf("Failed to read the error of deployment %s: %v", deploymentName, err)
+		return deploymentErr
+	}
+
+	result := newDeploymentError(fmt.Sprintf("deployment %s failed", deploymentName), deployment.Properties.Error)
+	result.CorrelationID = stringValue(deployment.Properties.CorrelationID)
+
+	operations, err := listOperations(ctx, operationsClient, options, deploymentName)
+	if err != nil {
+		logrus.Debugf("Failed to list the operations of deployment %s: %v", deploymentName, err)
+	}
+
+	for _, operation := range operations {
+		properties := operation.Properties
+		if properties == nil || !strings.EqualFold(stringValue(properties.ProvisioningState), string(resources.ProvisioningStateFailed)) {
+			continue
+		}
+
+		failed := FailedOperation{StatusCode: stringValue(properties.StatusCode)}
+		if properties.TargetResource != nil {
+			failed.ResourceID = stringValue(properties.TargetResource.ID)
+			failed.ResourceType = stringValue(properties.TargetResource.ResourceType)
+			failed.ResourceName = stringValue(properties.TargetResource.ResourceName)
+		}
+		if properties.StatusMessage != nil {
+			failed.Error = newErrorDetail(properties.StatusMessage.Error)
+		}
+
+		result.Operations = append(result.Operations, failed)
+	}
+
+	return result
+}
@@ -0,0 +1,47 @@
+package actions
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDeploymentErrorRootCauses(t *testing.T) {
+	deploymentErr := &DeploymentError{
+		Summary: "deployment app failed",
+		Errors: []ErrorDetail{{
+			Code:    "DeploymentFailed",
+			Message: "At least one resource deployment operation failed.",
+			Details: []ErrorDetail{{Code: "Conflict", Message: "storage account name taken"}},
+		}},
+		Operations: []FailedOperation{{
+			ResourceID: "/sa",
+			Error: &ErrorDetail{
+				Code: "BadRequest",
+				Details: []ErrorDetail{
+					{Code: "StorageAccountAlreadyTaken", Message: "name taken", Target: "name"},
+					{Code: "StorageAccountAlreadyTaken", Message: "name taken", Target: "name"},
+				},
+			},
+		}},
+	}
+
+	causes := deploymentErr.RootCauses()
+	if len(causes) != 1 {
+		t.Fatalf("Got unexpected root causes: %v", causes)
+	}
+	if got, want := causes[0].String(), "/sa: StorageAccountAlreadyTaken: name taken (target: name)"; got != want {
+		t.Errorf("Got invalid root cause, expected %s got %s", want, got)
+	}
+
+	message := deploymentErr.Error()
+	for _, want := range []string{"  - DeploymentFailed", "    - Conflict: storage account name taken", "Failed operations:"} {
+		if !strings.Contains(message, want) {
+			t.Errorf("Error message is missing %q:\n%s", want, message)
+		}
+	}
+
+	summaryOnly := (&DeploymentError{Summary: "validation failed"}).RootCauses()
+	if len(summaryOnly) != 1 || summaryOnly[0].Message != "validation failed" {
+		t.Errorf("Got unexpected root causes: %v", summaryOnly)
+	}
+}
@@ -0,0 +1,157 @@
+/*
+Copyright (c) 2020 white duck Gesellschaft für Softwareentwicklung mbH
+
+This code is licensed under MIT license (see LICENSE for details)
+*/
+package actions
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/whiteducksoftware/azure-arm-action/pkg/github"
+	"github.com/whiteducksoftware/azure-arm-action/pkg/github/io"
+	"gopkg.in/yaml.v3"
+)
+
+// invalidEnvCharacters matches everything which isn't allowed in the name of an environment variable
+var invalidEnvCharacters = regexp.MustCompile(`[^A-Za-z0-9_]+`)
+
+// ExportOutputs writes the outputs to every target of the exportTo input, which is either env
+// for the environment of the following steps or the path of a json, yaml or dotenv file
+func ExportOutputs(options github.Options, outputs map[string]Output) error {
+	for _, target := range options.ExportTo {
+		target = strings.TrimSpace(target)
+		switch {
+		case len(target) == 0:
+			continue
+		case strings.EqualFold(target, github.ExportToEnv):
+			if err := exportEnv(options, outputs); err != nil {
+				return err
+			}
+		default:
+			if err := exportFile(options, target, outputs); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// envName converts the output name to the name of the environment variable, e.g. storageAccount.id to ARM_STORAGEACCOUNT_ID
+func envName(prefix, name string) string {
+	return prefix + strings.ToUpper(invalidEnvCharacters.ReplaceAllString(name, "_"))
+}
+
+func exportEnv(options github.Options, outputs map[string]Output) error {
+	for _, name := range sortedOutputNames(outputs) {
+		if err := io.ExportVariable(envName(options.ExportEnvPrefix, name), outputs[name].String()); err != nil {
+			return fmt.Errorf("failed to export output %s: %v", name, err)
+		}
+	}
+
+	logrus.Infof("Exported %d outputs as environment variables with prefix %s", len(outputs), options.ExportEnvPrefix)
+	return nil
+}
+
+// exportFile writes the outputs to the file, secure outputs are left out as the file is usually uploaded as artifact
+func exportFile(options github.Options, path string, outputs map[string]Output) error {
+	values := make(map[string]interface{}, len(outputs))
+	for name, output := range outputs {
+		if !output.IsSecure() {
+			values[name] = output.Value
+		}
+	}
+
+	var err error
+	var data []byte
+	format := outputsFormat(options, path)
+	switch format {
+	case github.OutputsFormatYAML:
+		data, err = marshalYAML(values)
+	case github.OutputsFormatDotenv:
+		data = formatDotenv(options.ExportEnvPrefix, values)
+	default:
+		data, err = json.MarshalIndent(values, "", "  ")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to format the outputs as %s: %v", format, err)
+	}
+
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to export the outputs to %s: %v", path, err)
+	}
+
+	logrus.Infof("Exported %d outputs to %s (%s)", len(values), path, format)
+	return nil
+}
+
+// marshalYAML formats the value as yaml with an indentation of two spaces, like the json files
+func marshalYAML(value interface{}) ([]byte, error) {
+	var buffer bytes.Buffer
+	encoder := yaml.NewEncoder(&buffer)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(value); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// outputsFormat returns the format of the file, it's derived from the extension if outputsFormat isn't set
+func outputsFormat(options github.Options, path string) string {
+	if len(options.OutputsFormat) > 0 {
+		return strings.ToLower(options.OutputsFormat)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return github.OutputsFormatYAML
+	case ".env":
+		return github.OutputsFormatDotenv
+	default:
+		return github.OutputsFormatJSON
+	}
+}
+
+// formatDotenv writes one NAME=value line per output, values with special characters are quoted
+func formatDotenv(prefix string, values map[string]interface{}) []byte {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var sb strings.Builder
+	for _, name := range names {
+		value := Output{Value: values[name]}.String()
+		if strings.ContainsAny(value, " \t\r\n\"'\\#$=`") {
+			value = strconv.Quote(value)
+		}
+		fmt.Fprintf(&sb, "%s=%s\n", envName(prefix, name), value)
+	}
+
+	return []byte(sb.String())
+}
+
+func sortedOutputNames(outputs map[string]Output) []string {
+	names := make([]string, 0, len(outputs))
+	for name := range outputs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
@@ -0,0 +1,57 @@
+package actions
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/whiteducksoftware/azure-arm-action/pkg/github"
+)
+
+func TestExportOutputs(t *testing.T) {
+	dir := t.TempDir()
+	envFile := filepath.Join(dir, "env")
+	os.Setenv("GITHUB_ENV", envFile)
+	defer os.Unsetenv("GITHUB_ENV")
+
+	outputs := map[string]Output{
+		"storageAccount.id": {Type: "String", Value: "/subscriptions/1/sa"},
+		"ports":             {Type: "Array", Value: []interface{}{float64(80), float64(443)}},
+		"password":          {Type: "SecureString", Value: "secret"},
+	}
+
+	options := github.Options{Inputs: github.Inputs{
+		ExportTo:        []string{"env", filepath.Join(dir, "outputs.json"), filepath.Join(dir, "outputs.yml"), filepath.Join(dir, "outputs.env")},
+		ExportEnvPrefix: "ARM_",
+	}}
+	if err := ExportOutputs(options, outputs); err != nil {
+		t.Fatalf("Got unexpected error: %s", err)
+	}
+
+	tests := map[string]string{
+		"outputs.json": "{\n  \"ports\": [\n    80,\n    443\n  ],\n  \"storageAccount.id\": \"/subscriptions/1/sa\"\n}",
+		"outputs.yml":  "ports:\n  - 80\n  - 443\nstorageAccount.id: /subscriptions/1/sa\n",
+		"outputs.env":  "ARM_PORTS=[80,443]\nARM_STORAGEACCOUNT_ID=/subscriptions/1/sa\n",
+	}
+	for file, want := range tests {
+		data, err := ioutil.ReadFile(filepath.Join(dir, file))
+		if err != nil {
+			t.Fatalf("Got unexpected error: %s", err)
+		}
+		if string(data) != want {
+			t.Errorf("Got invalid %s, expected\n%s\ngot\n%s", file, want, data)
+		}
+	}
+
+	data, err := ioutil.ReadFile(envFile)
+	if err != nil {
+		t.Fatalf("Got unexpected error: %s", err)
+	}
+	for _, name := range []string{"ARM_PASSWORD<<", "ARM_PORTS<<", "ARM_STORAGEACCOUNT_ID<<"} {
+		if !strings.Contains(string(data), name) {
+			t.Errorf("Environment variable %s is missing in\n%s", name, data)
+		}
+	}
+}
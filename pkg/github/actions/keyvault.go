@@ -0,0 +1,80 @@
+/*
+Copyright (c) 2020 white duck Gesellschaft für Softwareentwicklung mbH
+
+This code is licensed under MIT license (see LICENSE for details)
+*/
+package actions
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/profiles/latest/resources/mgmt/resources"
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/sirupsen/logrus"
+	"github.com/whiteducksoftware/azure-arm-action/pkg/github"
+)
+
+// resolveKeyVaultReferences replaces the key vault references of the parameters with the reference
+// shape arm expects, vault names are resolved to the resource id of the vault in the subscription
+func resolveKeyVaultReferences(ctx context.Context, options github.Options, authorizer autorest.Authorizer, parameter map[string]interface{}) error {
+	client := resources.NewClientWithBaseURI(options.Credentials.ARMEndpointURL, options.Credentials.SubscriptionID)
+	client.Authorizer = authorizer
+
+	vaultIDs := make(map[string]string)
+	for name, value := range parameter {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		reference, ok := object["reference"].(github.KeyVaultReference)
+		if !ok {
+			continue
+		}
+
+		vaultID, ok := vaultIDs[strings.ToLower(reference.Vault)]
+		if !ok {
+			var err error
+			vaultID, err = keyVaultID(ctx, client, reference.Vault)
+			if err != nil {
+				return fmt.Errorf("failed to resolve the key vault of parameter %s: %v", name, err)
+			}
+			vaultIDs[strings.ToLower(reference.Vault)] = vaultID
+		}
+
+		resolved := map[string]interface{}{
+			"keyVault":   map[string]interface{}{"id": vaultID},
+			"secretName": reference.Secret,
+		}
+		if len(reference.Version) > 0 {
+			resolved["secretVersion"] = reference.Version
+		}
+		parameter[name] = map[string]interface{}{"reference": resolved}
+		logrus.Infof("Parameter %s references secret %s of key vault %s", name, reference.Secret, vaultID)
+	}
+
+	return nil
+}
+
+// keyVaultID returns the resource id of the vault, resource ids are returned as they are
+func keyVaultID(ctx context.Context, client resources.Client, vault string) (string, error) {
+	if strings.HasPrefix(strings.ToLower(vault), "/subscriptions/") {
+		return vault, nil
+	}
+
+	filter := fmt.Sprintf("resourceType eq 'Microsoft.KeyVault/vaults' and name eq '%s'", vault)
+	iterator, err := client.ListComplete(ctx, filter, "", nil)
+	for ; err == nil && iterator.NotDone(); err = iterator.NextWithContext(ctx) {
+		resource := iterator.Value()
+		if resource.ID != nil && strings.EqualFold(stringValue(resource.Name), vault) {
+			return *resource.ID, nil
+		}
+	}
+	if err != nil {
+		return "", fmt.Errorf("cannot list key vaults: %v", err)
+	}
+
+	return "", fmt.Errorf("key vault %s not found in subscription %s", vault, client.SubscriptionID)
+}
@@ -0,0 +1,55 @@
+package actions
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/whiteducksoftware/azure-arm-action/pkg/github"
+)
+
+func TestResolveKeyVaultReferences(t *testing.T) {
+	vaultID := "/subscriptions/" + testSubscriptionID + "/resourceGroups/secrets/providers/Microsoft.KeyVault/vaults/myvault"
+	lookups := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lookups++
+		// The vault is looked up with the casing of the parameter which is resolved first
+		if want := "resourceType eq 'Microsoft.KeyVault/vaults' and name eq 'myvault'"; !strings.EqualFold(r.URL.Query().Get("$filter"), want) {
+			t.Errorf("Got invalid filter, expected %s got %s", want, r.URL.Query().Get("$filter"))
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"value": []interface{}{map[string]interface{}{"id": vaultID, "name": "myvault"}},
+		})
+	}))
+	defer server.Close()
+
+	otherID := "/subscriptions/1/resourceGroups/other/providers/Microsoft.KeyVault/vaults/other"
+	parameter := map[string]interface{}{
+		"password": map[string]interface{}{"reference": github.KeyVaultReference{Vault: "myvault", Secret: "admin-password"}},
+		"cert":     map[string]interface{}{"reference": github.KeyVaultReference{Vault: "MyVault", Secret: "cert", Version: "v1"}},
+		"other":    map[string]interface{}{"reference": github.KeyVaultReference{Vault: otherID, Secret: "s"}},
+		"name":     map[string]interface{}{"value": "app"},
+	}
+
+	if err := resolveKeyVaultReferences(context.Background(), testOptions(server.URL), autorest.NullAuthorizer{}, parameter); err != nil {
+		t.Fatalf("Got unexpected error: %s", err)
+	}
+
+	got, _ := json.Marshal(parameter)
+	want := `{"cert":{"reference":{"keyVault":{"id":"` + vaultID + `"},"secretName":"cert","secretVersion":"v1"}},` +
+		`"name":{"value":"app"},` +
+		`"other":{"reference":{"keyVault":{"id":"` + otherID + `"},"secretName":"s"}},` +
+		`"password":{"reference":{"keyVault":{"id":"` + vaultID + `"},"secretName":"admin-password"}}}`
+	if string(got) != want {
+		t.Errorf("Got invalid parameters, expected\n%s\ngot\n%s", want, got)
+	}
+	if lookups != 1 {
+		t.Errorf("Got %d key vault lookups, expected 1", lookups)
+	}
+}
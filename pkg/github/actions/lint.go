@@ -0,0 +1,39 @@
+/*
+Copyright (c) 2020 white duck Gesellschaft für Softwareentwicklung mbH
+
+This code is licensed under MIT license (see LICENSE for details)
+*/
+package actions
+
+import (
+	"github.com/sirupsen/logrus"
+	"github.com/whiteducksoftware/azure-arm-action/pkg/github"
+	"github.com/whiteducksoftware/azure-arm-action/pkg/lint"
+)
+
+// Lint checks the template offline with the lint rules, no azure api is called
+func Lint(options github.Options) ([]lint.Finding, error) {
+	findings, err := lint.Run(options.Template, options.LintRules)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, finding := range findings {
+		logrus.Infof("[%s] %s: %s (%s)", finding.Severity, finding.Path, finding.Message, finding.Rule)
+	}
+	logrus.Infof("Lint finished with %d errors and %d findings in total.", CountFindings(findings, lint.SeverityError), len(findings))
+
+	return findings, nil
+}
+
+// CountFindings returns the number of findings with the severity
+func CountFindings(findings []lint.Finding, severity lint.Severity) int {
+	count := 0
+	for _, finding := range findings {
+		if finding.Severity == severity {
+			count++
+		}
+	}
+
+	return count
+}
@@ -0,0 +1,74 @@
+/*
+Copyright (c) 2020 white duck Gesellschaft für Softwareentwicklung mbH
+
+This code is licensed under MIT license (see LICENSE for details)
+*/
+package actions
+
+import (
+	"strings"
+
+	"github.com/whiteducksoftware/azure-arm-action/pkg/github/io"
+)
+
+// isSecureType checks if the arm type is securestring or secureobject
+func isSecureType(armType string) bool {
+	return strings.EqualFold(armType, "securestring") || strings.EqualFold(armType, "secureobject")
+}
+
+// IsSecure checks if the output is declared as securestring or secureobject
+func (output Output) IsSecure() bool {
+	return isSecureType(output.Type)
+}
+
+// MaskValue masks the strings in the value and the json of objects and arrays, so neither
+// the whole object nor a single flattened key shows up in the log. Numbers and bools
+// aren't masked, github would hide every occurrence of e.g. true in the log otherwise.
+func MaskValue(value interface{}) {
+	switch value := value.(type) {
+	case string:
+		io.AddMask(value)
+	case map[string]interface{}:
+		io.AddMask(Output{Value: value}.String())
+		for _, nested := range value {
+			MaskValue(nested)
+		}
+	case []interface{}:
+		io.AddMask(Output{Value: value}.String())
+		for _, nested := range value {
+			MaskValue(nested)
+		}
+	}
+}
+
+// maskSecureParameters masks the values of all parameters which the template declares as secure,
+// linked templates and template specs are unknown locally, so their parameters can't be masked
+func maskSecureParameters(template map[string]interface{}, parameters map[string]interface{}) {
+	declarations, ok := template["parameters"].(map[string]interface{})
+	if !ok {
+		return
+	}
+
+	for name, declaration := range declarations {
+		declaration, ok := declaration.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		armType, _ := declaration["type"].(string)
+		if !isSecureType(armType) {
+			continue
+		}
+
+		// Key vault references have no value, only the reference
+		for key, parameter := range parameters {
+			if !strings.EqualFold(key, name) {
+				continue
+			}
+
+			if parameter, ok := parameter.(map[string]interface{}); ok && parameter["value"] != nil {
+				MaskValue(parameter["value"])
+			}
+		}
+	}
+}
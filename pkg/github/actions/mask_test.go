@@ -0,0 +1,69 @@
+package actions
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+// captureStdout returns everything f prints, the workflow commands are written to stdout
+func captureStdout(t *testing.T, f func()) string {
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Got unexpected error: %s", err)
+	}
+
+	stdout := os.Stdout
+	os.Stdout = writer
+	f()
+	os.Stdout = stdout
+	writer.Close()
+
+	var buf bytes.Buffer
+	if _, err := io.Copy(&buf, reader); err != nil {
+		t.Fatalf("Got unexpected error: %s", err)
+	}
+	return buf.String()
+}
+
+func TestMaskSecureParameters(t *testing.T) {
+	template := map[string]interface{}{
+		"parameters": map[string]interface{}{
+			"connectionString": map[string]interface{}{"type": "secureString"},
+			"settings":         map[string]interface{}{"type": "secureObject"},
+			"location":         map[string]interface{}{"type": "string"},
+		},
+	}
+	parameters := map[string]interface{}{
+		"connectionString": map[string]interface{}{"value": "Password=test"},
+		"settings":         map[string]interface{}{"value": map[string]interface{}{"key": "secret", "enabled": true}},
+		"location":         map[string]interface{}{"value": "westeurope"},
+	}
+
+	out := captureStdout(t, func() { maskSecureParameters(template, parameters) })
+
+	for _, want := range []string{"::add-mask::Password=test\n", "::add-mask::secret\n", `::add-mask::{"enabled":true,"key":"secret"}` + "\n"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Secure value is not masked, expected %q in %q", want, out)
+		}
+	}
+	for _, unwanted := range []string{"westeurope", "::add-mask::true"} {
+		if strings.Contains(out, unwanted) {
+			t.Errorf("Got unexpected mask %q in %q", unwanted, out)
+		}
+	}
+}
+
+func TestFlattenSecureOutputs(t *testing.T) {
+	flattened := FlattenOutputs(map[string]Output{
+		"settings": {Type: "SecureObject", Value: map[string]interface{}{"key": "secret", "nested": map[string]interface{}{}}},
+	})
+
+	for _, name := range []string{"settings", "settings.key", "settings.nested"} {
+		if !flattened[name].IsSecure() {
+			t.Errorf("Flattened output %s of secure object is not secure", name)
+		}
+	}
+}
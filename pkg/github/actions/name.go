@@ -0,0 +1,114 @@
+/*
+Copyright (c) 2020 white duck Gesellschaft für Softwareentwicklung mbH
+
+This code is licensed under MIT license (see LICENSE for details)
+*/
+package actions
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/google/uuid"
+	"github.com/whiteducksoftware/azure-arm-action/pkg/github"
+	"github.com/whiteducksoftware/golang-utilities/github/actions"
+)
+
+// maxDeploymentNameLength is the maximum length of a deployment name allowed by arm
+const maxDeploymentNameLength = 64
+
+// invalidDeploymentNameChars matches all characters which arm doesn't allow in deployment names
+var invalidDeploymentNameChars = regexp.MustCompile(`[^\w\-\.\(\)]+`)
+
+// deploymentNameData is passed to deployment name templates
+type deploymentNameData struct {
+	actions.GitHub
+	github.Run
+	Name        string
+	UUID        string
+	ShortCommit string
+}
+
+// DeploymentName builds the name of the deployment according to the deploymentNameStrategy
+func DeploymentName(options github.Options) (string, error) {
+	switch strings.ToLower(options.DeploymentNameStrategy) {
+	case "", github.NameStrategyUUID:
+		return joinDeploymentName(options.DeploymentName, uuid.New().String()), nil
+	case github.NameStrategyExact:
+		if len(options.DeploymentName) == 0 {
+			return "", fmt.Errorf("deployment name strategy %s requires deploymentName", github.NameStrategyExact)
+		}
+		return joinDeploymentName(options.DeploymentName, ""), nil
+	case github.NameStrategyRun:
+		return joinDeploymentName(options.DeploymentName, fmt.Sprintf("%d-%d", options.RunID, options.RunAttempt)), nil
+	case github.NameStrategyCommit:
+		return joinDeploymentName(options.DeploymentName, shortCommit(options.Commit)), nil
+	}
+
+	// Everything else is a go template
+	tmpl, err := template.New("deploymentName").Option("missingkey=error").Parse(options.DeploymentNameStrategy)
+	if err != nil {
+		return "", fmt.Errorf("invalid deployment name template: %s", err)
+	}
+
+	var sb strings.Builder
+	err = tmpl.Execute(&sb, deploymentNameData{
+		GitHub:      options.GitHub,
+		Run:         options.Run,
+		Name:        options.DeploymentName,
+		UUID:        uuid.New().String(),
+		ShortCommit: shortCommit(options.Commit),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to render deployment name template: %s", err)
+	}
+
+	name := sanitizeDeploymentName(sb.String())
+	if len(name) > maxDeploymentNameLength {
+		name = strings.Trim(name[:maxDeploymentNameLength], "-.")
+	}
+	if len(name) == 0 {
+		return "", fmt.Errorf("the deployment name template rendered an empty name")
+	}
+
+	return name, nil
+}
+
+// joinDeploymentName joins the base name and suffix, the base name is truncated
+// so that the suffix always stays intact within the length limit
+func joinDeploymentName(name, suffix string) string {
+	name = sanitizeDeploymentName(name)
+	suffix = sanitizeDeploymentName(suffix)
+
+	switch {
+	case len(suffix) == 0:
+		if len(name) > maxDeploymentNameLength {
+			name = strings.Trim(name[:maxDeploymentNameLength], "-.")
+		}
+		return name
+	case len(name) == 0:
+		return suffix
+	}
+
+	if maxNameLength := maxDeploymentNameLength - len(suffix) - 1; len(name) > maxNameLength {
+		name = strings.Trim(name[:maxNameLength], "-.")
+	}
+
+	return fmt.Sprintf("%s-%s", name, suffix)
+}
+
+// sanitizeDeploymentName replaces all characters which are not allowed by arm
+func sanitizeDeploymentName(name string) string {
+	name = invalidDeploymentNameChars.ReplaceAllString(strings.TrimSpace(name), "-")
+	return strings.TrimRight(name, ".")
+}
+
+func shortCommit(commit string) string {
+	if len(commit) > 7 {
+		return commit[:7]
+	}
+
+	return commit
+}
@@ -0,0 +1,61 @@
+package actions
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/whiteducksoftware/azure-arm-action/pkg/github"
+	"github.com/whiteducksoftware/golang-utilities/github/actions"
+)
+
+func TestDeploymentName(t *testing.T) {
+	uuidPattern := `[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}`
+	longName := strings.Repeat("a", 80)
+
+	tests := []struct {
+		name     string
+		base     string
+		strategy string
+		want     string
+	}{
+		{name: "uuid", base: "app", strategy: "uuid", want: `^app-` + uuidPattern + `$`},
+		{name: "default", base: "app", strategy: "", want: `^app-` + uuidPattern + `$`},
+		{name: "uuid truncated", base: longName, strategy: "uuid", want: `^a{27}-` + uuidPattern + `$`},
+		{name: "exact", base: "app", strategy: "exact", want: `^app$`},
+		{name: "exact truncated", base: longName, strategy: "exact", want: `^a{64}$`},
+		{name: "exact sanitized", base: "my app/prod", strategy: "Exact", want: `^my-app-prod$`},
+		{name: "run", base: "app", strategy: "run", want: `^app-4711-2$`},
+		{name: "commit", base: "app", strategy: "commit", want: `^app-0123456$`},
+		{name: "template", base: "app", strategy: "{{ .Name }}-{{ .Workflow }}-{{ .RunNumber }}", want: `^app-deploy-infra-42$`},
+		{name: "template truncated", base: longName, strategy: "{{ .Name }}-{{ .ShortCommit }}", want: `^a{64}$`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			options := github.Options{
+				GitHub: actions.GitHub{Workflow: "deploy infra", RunID: 4711, Commit: "0123456789abcdef"},
+				Run:    github.Run{RunNumber: 42, RunAttempt: 2},
+				Inputs: github.Inputs{DeploymentName: test.base, DeploymentNameStrategy: test.strategy},
+			}
+
+			name, err := DeploymentName(options)
+			if err != nil {
+				t.Fatalf("Got unexpected error: %s", err)
+			}
+
+			if !regexp.MustCompile(test.want).MatchString(name) {
+				t.Errorf("Got invalid deployment name, expected %s got %s", test.want, name)
+			}
+		})
+	}
+}
+
+func TestDeploymentNameErrors(t *testing.T) {
+	for _, strategy := range []string{"exact", "{{ .Missing }}", "{{ .Name "} {
+		options := github.Options{Inputs: github.Inputs{DeploymentNameStrategy: strategy}}
+		if _, err := DeploymentName(options); err == nil {
+			t.Errorf("Got no error for strategy %s", strategy)
+		}
+	}
+}
@@ -0,0 +1,272 @@
+/*
+Copyright (c) 2020 white duck Gesellschaft für Softwareentwicklung mbH
+
+This code is licensed under MIT license (see LICENSE for details)
+*/
+package actions
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/whiteducksoftware/azure-arm-action/pkg/github"
+)
+
+// validateParameters checks the merged parameters against the parameters the template declares: missing required parameters,
+// unknown names, types, allowed values, lengths and values. Issues of parameters which are only set by overrideParameters
+// are reported in a separate group. Linked templates and template specs are unknown locally and aren't checked.
+func validateParameters(template map[string]interface{}, parameter map[string]interface{}, provenance map[string][]string) error {
+	if template == nil {
+		return nil
+	}
+	declarations, _ := template["parameters"].(map[string]interface{})
+
+	var issues, overrideIssues []ErrorDetail
+	report := func(name string, issue ErrorDetail) {
+		issue.Target = name
+		if layers := provenance[name]; len(layers) == 1 && layers[0] == github.OverrideParametersLayer {
+			overrideIssues = append(overrideIssues, issue)
+			return
+		}
+		issues = append(issues, issue)
+	}
+
+	for _, name := range sortedKeys(declarations) {
+		declaration, _ := declarations[name].(map[string]interface{})
+		value, found := lookupParameter(parameter, name)
+		if !found {
+			if _, hasDefault := declaration["defaultValue"]; !hasDefault {
+				report(name, ErrorDetail{Code: "MissingParameter", Message: fmt.Sprintf("parameter %s is required, it has no default value", name)})
+			}
+			continue
+		}
+
+		object, _ := value.(map[string]interface{})
+		parameterValue, hasValue := object["value"]
+		if !hasValue {
+			// Key vault references are resolved by arm
+			continue
+		}
+
+		for _, message := range checkParameterValue(declaration, parameterValue) {
+			report(name, ErrorDetail{Code: "InvalidParameter", Message: fmt.Sprintf("parameter %s %s", name, message)})
+		}
+	}
+
+	for _, name := range sortedKeys(parameter) {
+		if _, declared := lookupParameter(declarations, name); declared {
+			continue
+		}
+
+		message := fmt.Sprintf("parameter %s is not declared by the template", name)
+		if suggestion := closestName(name, declarations); len(suggestion) > 0 {
+			message = fmt.Sprintf("%s, did you mean %s?", message, suggestion)
+		}
+		report(name, ErrorDetail{Code: "UnknownParameter", Message: message})
+	}
+
+	if len(issues) == 0 && len(overrideIssues) == 0 {
+		return nil
+	}
+
+	deploymentErr := &DeploymentError{Summary: "parameters don't match the template"}
+	if len(issues) > 0 {
+		deploymentErr.Errors = append(deploymentErr.Errors, ErrorDetail{Code: "InvalidParameters", Message: "invalid parameters", Details: issues})
+	}
+	if len(overrideIssues) > 0 {
+		deploymentErr.Errors = append(deploymentErr.Errors, ErrorDetail{Code: "InvalidOverrideParameters", Message: "invalid parameters set only by overrideParameters", Details: overrideIssues})
+	}
+
+	return deploymentErr
+}
+
+// checkParameterValue returns the violations of the declared type, allowed values, lengths and values
+func checkParameterValue(declaration map[string]interface{}, value interface{}) []string {
+	parameterType, _ := declaration["type"].(string)
+	parameterType = strings.ToLower(parameterType)
+
+	// Values of secure parameters must not show up in the log, annotations or job summary
+	describe := describeValue
+	if isSecureType(parameterType) {
+		describe = func(interface{}) string { return "a secure value" }
+	}
+
+	if !matchesType(parameterType, value) {
+		return []string{fmt.Sprintf("must be of type %s, got %s", declaration["type"], describe(value))}
+	}
+
+	var messages []string
+	if allowed, ok := declaration["allowedValues"].([]interface{}); ok {
+		// arm checks every item of arrays against the allowed values
+		items := []interface{}{value}
+		if array, ok := value.([]interface{}); ok && parameterType == "array" {
+			items = array
+		}
+
+		for _, item := range items {
+			if !containsValue(allowed, item) {
+				messages = append(messages, fmt.Sprintf("value %s is not allowed, expected one of %s", describe(item), describeValue(allowed)))
+			}
+		}
+	}
+
+	length := -1
+	switch value := value.(type) {
+	case string:
+		length = len([]rune(value))
+	case []interface{}:
+		length = len(value)
+	}
+	if min, ok := numberValue(declaration["minLength"]); ok && length >= 0 && float64(length) < min {
+		messages = append(messages, fmt.Sprintf("must have a length of at least %v, got %d", min, length))
+	}
+	if max, ok := numberValue(declaration["maxLength"]); ok && length >= 0 && float64(length) > max {
+		messages = append(messages, fmt.Sprintf("must have a length of at most %v, got %d", max, length))
+	}
+
+	if number, isNumber := numberValue(value); isNumber {
+		if min, ok := numberValue(declaration["minValue"]); ok && number < min {
+			messages = append(messages, fmt.Sprintf("must be at least %v, got %v", min, number))
+		}
+		if max, ok := numberValue(declaration["maxValue"]); ok && number > max {
+			messages = append(messages, fmt.Sprintf("must be at most %v, got %v", max, number))
+		}
+	}
+
+	return messages
+}
+
+func matchesType(parameterType string, value interface{}) bool {
+	switch parameterType {
+	case "string", "securestring":
+		_, ok := value.(string)
+		return ok
+	case "int":
+		number, ok := numberValue(value)
+		return ok && number == math.Trunc(number)
+	case "bool":
+		_, ok := value.(bool)
+		return ok
+	case "object", "secureobject":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	default:
+		return true
+	}
+}
+
+// numberValue converts the numbers of json, yaml and coerced raw parameters
+func numberValue(value interface{}) (float64, bool) {
+	switch value := value.(type) {
+	case float64:
+		return value, true
+	case int:
+		return float64(value), true
+	case int64:
+		return float64(value), true
+	case json.Number:
+		number, err := value.Float64()
+		return number, err == nil
+	default:
+		return 0, false
+	}
+}
+
+func containsValue(allowed []interface{}, value interface{}) bool {
+	for _, candidate := range allowed {
+		if describeValue(candidate) == describeValue(value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// describeValue formats the value as json, so numbers of different types compare equal
+func describeValue(value interface{}) string {
+	if number, ok := numberValue(value); ok {
+		return formatNumber(number)
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
+
+// lookupParameter finds the parameter case-insensitive, like arm does
+func lookupParameter(parameter map[string]interface{}, name string) (interface{}, bool) {
+	if value, ok := parameter[name]; ok {
+		return value, true
+	}
+
+	for key, value := range parameter {
+		if strings.EqualFold(key, name) {
+			return value, true
+		}
+	}
+
+	return nil, false
+}
+
+// closestName returns the declared parameter with the smallest edit distance, if it's close enough to be a typo
+func closestName(name string, declarations map[string]interface{}) string {
+	best, bestDistance := "", 3
+	for _, candidate := range sortedKeys(declarations) {
+		if distance := editDistance(strings.ToLower(name), strings.ToLower(candidate)); distance < bestDistance {
+			best, bestDistance = candidate, distance
+		}
+	}
+
+	return best
+}
+
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current := make([]int, len(b)+1)
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = minInt(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous = current
+	}
+
+	return previous[len(b)]
+}
+
+func minInt(values ...int) int {
+	min := values[0]
+	for _, value := range values[1:] {
+		if value < min {
+			min = value
+		}
+	}
+
+	return min
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
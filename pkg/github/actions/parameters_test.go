@@ -0,0 +1,100 @@
+package actions
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/whiteducksoftware/azure-arm-action/pkg/github"
+)
+
+func TestValidateParameters(t *testing.T) {
+	template := map[string]interface{}{
+		"parameters": map[string]interface{}{
+			"storageName": map[string]interface{}{"type": "string", "minLength": float64(3), "maxLength": float64(24)},
+			"sku":         map[string]interface{}{"type": "string", "allowedValues": []interface{}{"Standard_LRS", "Standard_GRS"}, "defaultValue": "Standard_LRS"},
+			"capacity":    map[string]interface{}{"type": "int", "minValue": float64(1), "maxValue": float64(10)},
+			"zones":       map[string]interface{}{"type": "array", "allowedValues": []interface{}{"1", "2", "3"}, "defaultValue": []interface{}{}},
+			"password":    map[string]interface{}{"type": "securestring"},
+			"enabled":     map[string]interface{}{"type": "bool", "defaultValue": true},
+		},
+	}
+
+	valid := map[string]interface{}{
+		"storageName": map[string]interface{}{"value": "mystorage"},
+		"capacity":    map[string]interface{}{"value": int64(2)},
+		"zones":       map[string]interface{}{"value": []interface{}{"1", "3"}},
+		"Password":    map[string]interface{}{"reference": map[string]interface{}{"secretName": "password"}},
+	}
+	if err := validateParameters(template, valid, nil); err != nil {
+		t.Errorf("Got unexpected error: %s", err)
+	}
+
+	invalid := map[string]interface{}{
+		"storageName": map[string]interface{}{"value": "st"},
+		"sku":         map[string]interface{}{"value": "Premium_LRS"},
+		"capacity":    map[string]interface{}{"value": float64(11)},
+		"zones":       map[string]interface{}{"value": []interface{}{"1", "4"}},
+		"enabled":     map[string]interface{}{"value": "yes"},
+		"storageNme":  map[string]interface{}{"value": "typo"},
+	}
+	provenance := map[string][]string{"capacity": {"parameters.json", github.OverrideParametersLayer}, "enabled": {github.OverrideParametersLayer}}
+
+	err := validateParameters(template, invalid, provenance)
+	var deploymentErr *DeploymentError
+	if !errors.As(err, &deploymentErr) {
+		t.Fatalf("Got no deployment error: %v", err)
+	}
+
+	var causes []string
+	for _, cause := range deploymentErr.RootCauses() {
+		causes = append(causes, cause.String())
+	}
+	want := []string{
+		"InvalidParameter: parameter capacity must be at most 10, got 11 (target: capacity)",
+		"MissingParameter: parameter password is required, it has no default value (target: password)",
+		"InvalidParameter: parameter sku value \"Premium_LRS\" is not allowed, expected one of [\"Standard_LRS\",\"Standard_GRS\"] (target: sku)",
+		"InvalidParameter: parameter storageName must have a length of at least 3, got 2 (target: storageName)",
+		"InvalidParameter: parameter zones value \"4\" is not allowed, expected one of [\"1\",\"2\",\"3\"] (target: zones)",
+		"UnknownParameter: parameter storageNme is not declared by the template, did you mean storageName? (target: storageNme)",
+		"InvalidParameter: parameter enabled must be of type bool, got \"yes\" (target: enabled)",
+	}
+	if strings.Join(causes, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Got invalid issues, expected\n%s\ngot\n%s", strings.Join(want, "\n"), strings.Join(causes, "\n"))
+	}
+
+	if !strings.Contains(err.Error(), "InvalidOverrideParameters: invalid parameters set only by overrideParameters\n    - InvalidParameter: parameter enabled") {
+		t.Errorf("Override issues are not reported separately:\n%s", err)
+	}
+
+	if err := validateParameters(nil, invalid, nil); err != nil {
+		t.Errorf("Got error for an unknown template: %s", err)
+	}
+}
+
+func TestValidateSecureParameters(t *testing.T) {
+	template := map[string]interface{}{
+		"parameters": map[string]interface{}{
+			"pw":     map[string]interface{}{"type": "securestring", "allowedValues": []interface{}{"a", "b"}},
+			"config": map[string]interface{}{"type": "secureobject"},
+		},
+	}
+	parameter := map[string]interface{}{
+		"pw":     map[string]interface{}{"value": "hunter2"},
+		"config": map[string]interface{}{"value": "hunter3"},
+	}
+
+	err := validateParameters(template, parameter, nil)
+	if err == nil {
+		t.Fatal("Got no error for invalid secure parameters")
+	}
+
+	for _, secret := range []string{"hunter2", "hunter3"} {
+		if strings.Contains(err.Error(), secret) {
+			t.Errorf("Secure value %s is part of the error:\n%s", secret, err)
+		}
+	}
+	if !strings.Contains(err.Error(), "parameter pw value a secure value is not allowed") {
+		t.Errorf("Got invalid error:\n%s", err)
+	}
+}
@@ -5,16 +5,57 @@ This code is licensed under MIT license (see LICENSE for details)
 */
 package actions
 
-import "github.com/mitchellh/mapstructure"
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
 
-// Output represents a single output of an ARM template
+	"github.com/mitchellh/mapstructure"
+)
+
+// Output represents a single output of an ARM template, the value keeps the type arm returned
 type Output struct {
-	Type  string `json:"type"`
-	Value string `json:"value"`
+	Type  string      `json:"type"`
+	Value interface{} `json:"value"`
+}
+
+// String formats the value for the github outputs, objects and arrays are serialized
+// as compact json, numbers and bools are formatted canonically
+func (output Output) String() string {
+	switch value := output.Value.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	case bool:
+		return strconv.FormatBool(value)
+	case float64:
+		return formatNumber(value)
+	case json.Number:
+		return value.String()
+	case int, int32, int64:
+		return fmt.Sprint(value)
+	default:
+		data, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Sprint(value)
+		}
+		return string(data)
+	}
+}
+
+// formatNumber prints integers without fraction and exponent, json numbers are decoded as float64
+func formatNumber(value float64) string {
+	if value == math.Trunc(value) && math.Abs(value) < 1e21 {
+		return strconv.FormatFloat(value, 'f', 0, 64)
+	}
+
+	return strconv.FormatFloat(value, 'g', -1, 64)
 }
 
 // ParseOutputs takes the raw outputs from the azure.DemploymentExtended object
-// and converts it to a string Output map
+// and converts it to an Output map
 func ParseOutputs(raw interface{}) (map[string]Output, error) {
 	if raw == nil {
 		return map[string]Output{}, nil
@@ -36,3 +77,51 @@ func ParseOutputs(raw interface{}) (map[string]Output, error) {
 
 	return outputs, nil
 }
+
+// FlattenOutputs adds an output name.key for every key of object outputs, nested
+// objects are flattened recursively, the object outputs themselves are kept
+func FlattenOutputs(outputs map[string]Output) map[string]Output {
+	flattened := make(map[string]Output, len(outputs))
+	for name, output := range outputs {
+		flattenOutput(flattened, name, output)
+	}
+
+	return flattened
+}
+
+func flattenOutput(flattened map[string]Output, name string, output Output) {
+	flattened[name] = output
+
+	object, ok := output.Value.(map[string]interface{})
+	if !ok {
+		return
+	}
+
+	for key, value := range object {
+		nested := Output{Type: outputType(value), Value: value}
+		// Keys of secure objects are secure as well
+		if output.IsSecure() && nested.Type == "Object" {
+			nested.Type = "SecureObject"
+		} else if output.IsSecure() {
+			nested.Type = "SecureString"
+		}
+
+		flattenOutput(flattened, fmt.Sprintf("%s.%s", name, key), nested)
+	}
+}
+
+// outputType returns the arm type of a nested value
+func outputType(value interface{}) string {
+	switch value.(type) {
+	case string:
+		return "String"
+	case bool:
+		return "Bool"
+	case float64, json.Number, int, int32, int64:
+		return "Int"
+	case []interface{}:
+		return "Array"
+	default:
+		return "Object"
+	}
+}
@@ -0,0 +1,56 @@
+package actions
+
+import "testing"
+
+func TestOutputString(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		want  string
+	}{
+		{value: "westeurope", want: "westeurope"},
+		{value: true, want: "true"},
+		{value: float64(42), want: "42"},
+		{value: float64(1e15), want: "1000000000000000"},
+		{value: 1.5, want: "1.5"},
+		{value: []interface{}{"/id/1", "/id/2"}, want: `["/id/1","/id/2"]`},
+		{value: map[string]interface{}{"b": float64(1), "a": map[string]interface{}{"c": false}}, want: `{"a":{"c":false},"b":1}`},
+		{value: nil, want: ""},
+	}
+
+	for _, test := range tests {
+		if got := (Output{Value: test.value}).String(); got != test.want {
+			t.Errorf("Got invalid output value, expected %s got %s", test.want, got)
+		}
+	}
+}
+
+func TestFlattenOutputs(t *testing.T) {
+	outputs, err := ParseOutputs(map[string]interface{}{
+		"config": map[string]interface{}{
+			"type":  "Object",
+			"value": map[string]interface{}{"name": "app", "network": map[string]interface{}{"port": float64(443)}},
+		},
+		"ids": map[string]interface{}{"type": "Array", "value": []interface{}{"/id/1"}},
+	})
+	if err != nil {
+		t.Fatalf("Got unexpected error: %s", err)
+	}
+
+	flattened := FlattenOutputs(outputs)
+	want := map[string]string{
+		"config":              `{"name":"app","network":{"port":443}}`,
+		"config.name":         "app",
+		"config.network":      `{"port":443}`,
+		"config.network.port": "443",
+		"ids":                 `["/id/1"]`,
+	}
+
+	if len(flattened) != len(want) {
+		t.Errorf("Got invalid count of outputs, expected %d got %d", len(want), len(flattened))
+	}
+	for name, value := range want {
+		if got := flattened[name].String(); got != value {
+			t.Errorf("Got invalid value for %s, expected %s got %s", name, value, got)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+/*
+Copyright (c) 2020 white duck Gesellschaft für Softwareentwicklung mbH
+
+This code is licensed under MIT license (see LICENSE for details)
+*/
+package actions
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/profiles/latest/resources/mgmt/resources"
+	"github.com/sirupsen/logrus"
+	"github.com/whiteducksoftware/azure-arm-action/pkg/github"
+	"github.com/whiteducksoftware/azure-arm-action/pkg/github/io"
+)
+
+// progressTracker streams the state of the deployment operations to the log
+type progressTracker struct {
+	client         resources.DeploymentOperationsClient
+	options        github.Options
+	deploymentName string
+	states         map[string]string
+}
+
+// watchProgress polls the operations of the deployment in the background until the returned stop function is called,
+// stop prints the final state of all operations which changed since the last poll
+func watchProgress(ctx context.Context, client resources.DeploymentOperationsClient, options github.Options, deploymentName string) (stop func()) {
+	if options.ProgressInterval <= 0 {
+		return func() {}
+	}
+
+	tracker := &progressTracker{
+		client:         client,
+		options:        options,
+		deploymentName: deploymentName,
+		states:         make(map[string]string),
+	}
+
+	pollCtx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(options.ProgressInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-pollCtx.Done():
+				return
+			case <-ticker.C:
+				tracker.poll(pollCtx)
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+		wg.Wait()
+		if ctx.Err() == nil {
+			tracker.poll(ctx)
+		}
+	}
+}
+
+// poll lists the operations and prints every operation whose state changed since the last poll.
+// Listing errors are logged at debug level and the next poll tries again.
+func (tracker *progressTracker) poll(ctx context.Context) {
+	operations, err := listOperations(ctx, tracker.client, tracker.options, tracker.deploymentName)
+	if err != nil {
+		if ctx.Err() == nil {
+			logrus.Debugf("Failed to list the deployment operations: %s", err)
+		}
+		return
+	}
+
+	sort.SliceStable(operations, func(i, j int) bool {
+		return operationTime(operations[i]).Before(operationTime(operations[j]))
+	})
+
+	for _, operation := range operations {
+		properties := operation.Properties
+		if properties == nil || properties.TargetResource == nil || operation.OperationID == nil {
+			continue
+		}
+
+		state := fmt.Sprintf("%s/%s", stringValue(properties.ProvisioningState), stringValue(properties.StatusCode))
+		if tracker.states[*operation.OperationID] == state {
+			continue
+		}
+		tracker.states[*operation.OperationID] = state
+
+		printOperation(properties)
+	}
+}
+
+// listOperations lists the operations of the deployment at the scope selected by the inputs
+func listOperations(ctx context.Context, client resources.DeploymentOperationsClient, options github.Options, deploymentName string) ([]resources.DeploymentOperation, error) {
+	var err error
+	var iterator resources.DeploymentOperationsListResultIterator
+	switch options.Scope {
+	case github.ScopeResourceGroup:
+		iterator, err = client.ListComplete(ctx, options.ResourceGroupName, deploymentName, nil)
+	case github.ScopeManagementGroup:
+		iterator, err = client.ListAtManagementGroupScopeComplete(ctx, options.ManagementGroupId, deploymentName, nil)
+	case github.ScopeTenant:
+		iterator, err = client.ListAtTenantScopeComplete(ctx, deploymentName, nil)
+	default:
+		iterator, err = client.ListAtSubscriptionScopeComplete(ctx, deploymentName, nil)
+	}
+
+	var operations []resources.DeploymentOperation
+	for ; err == nil && iterator.NotDone(); err = iterator.NextWithContext(ctx) {
+		operations = append(operations, iterator.Value())
+	}
+
+	return operations, err
+}
+
+// printOperation prints the state of a single operation as collapsible group
+func printOperation(properties *resources.DeploymentOperationProperties) {
+	target := properties.TargetResource
+	title := fmt.Sprintf("%s %s/%s", stringValue(properties.ProvisioningState), stringValue(target.ResourceType), stringValue(target.ResourceName))
+	if properties.Duration != nil {
+		title = fmt.Sprintf("%s (%s)", title, *properties.Duration)
+	}
+
+	io.StartGroup(title)
+	fmt.Printf("Resource: %s\n", stringValue(target.ID))
+	if len(properties.ProvisioningOperation) > 0 {
+		fmt.Printf("Operation: %s\n", properties.ProvisioningOperation)
+	}
+	if properties.StatusCode != nil {
+		fmt.Printf("Status code: %s\n", *properties.StatusCode)
+	}
+	if message := properties.StatusMessage; message != nil {
+		if message.Status != nil {
+			fmt.Printf("Status: %s\n", *message.Status)
+		}
+		if message.Error != nil {
+			fmt.Printf("Error: %s %s\n", stringValue(message.Error.Code), stringValue(message.Error.Message))
+		}
+	}
+	io.EndGroup()
+}
+
+func operationTime(operation resources.DeploymentOperation) time.Time {
+	if operation.Properties == nil || operation.Properties.Timestamp == nil {
+		return time.Time{}
+	}
+
+	return operation.Properties.Timestamp.Time
+}
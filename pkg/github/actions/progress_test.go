@@ -0,0 +1,87 @@
+package actions
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/profiles/latest/resources/mgmt/resources"
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/whiteducksoftware/azure-arm-action/pkg/github"
+)
+
+func TestProgressPoll(t *testing.T) {
+	var mu sync.Mutex
+	storageState := "Running"
+	operation := func(id, resourceType, name, state, timestamp string) map[string]interface{} {
+		return map[string]interface{}{
+			"id":          "/operations/" + id,
+			"operationId": id,
+			"properties": map[string]interface{}{
+				"provisioningState":     state,
+				"provisioningOperation": "Create",
+				"timestamp":             timestamp,
+				"targetResource": map[string]interface{}{
+					"id":           "/" + resourceType + "/" + name,
+					"resourceType": resourceType,
+					"resourceName": name,
+				},
+			},
+		}
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/deployments/test/operations") {
+			t.Errorf("Got unexpected request: %s %s", r.Method, r.URL.Path)
+		}
+
+		mu.Lock()
+		state := storageState
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"value": []interface{}{
+				operation("2", "Microsoft.Web/sites", "app", "Running", "2020-01-01T00:00:02Z"),
+				operation("1", "Microsoft.Storage/storageAccounts", "sa", state, "2020-01-01T00:00:01Z"),
+			},
+		})
+	}))
+	defer server.Close()
+
+	options := testOptions(server.URL)
+	options.Scope = github.ScopeResourceGroup
+	options.ResourceGroupName = "my-group"
+	client := resources.NewDeploymentOperationsClientWithBaseURI(server.URL, testSubscriptionID)
+	client.Authorizer = autorest.NullAuthorizer{}
+
+	tracker := &progressTracker{client: client, options: options, deploymentName: "test", states: make(map[string]string)}
+
+	first := captureStdout(t, func() { tracker.poll(context.Background()) })
+	if got := strings.Count(first, "::group::"); got != 2 {
+		t.Fatalf("Got invalid number of groups for the first poll, expected 2 got %d:\n%s", got, first)
+	}
+	// Operations are printed in the order in which they happened
+	if strings.Index(first, "storageAccounts/sa") > strings.Index(first, "sites/app") {
+		t.Errorf("Got operations out of order:\n%s", first)
+	}
+
+	mu.Lock()
+	storageState = "Succeeded"
+	mu.Unlock()
+
+	second := captureStdout(t, func() { tracker.poll(context.Background()) })
+	if got := strings.Count(second, "::group::"); got != 1 {
+		t.Fatalf("Got invalid number of groups for the second poll, expected 1 got %d:\n%s", got, second)
+	}
+	if want := "::group::Succeeded Microsoft.Storage/storageAccounts/sa"; !strings.Contains(second, want) {
+		t.Errorf("Got invalid group, expected %s got:\n%s", want, second)
+	}
+	if want := "Resource: /Microsoft.Storage/storageAccounts/sa"; !strings.Contains(second, want) {
+		t.Errorf("Got invalid group content, expected %s got:\n%s", want, second)
+	}
+}
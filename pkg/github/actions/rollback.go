@@ -0,0 +1,108 @@
+/*
+Copyright (c) 2020 white duck Gesellschaft für Softwareentwicklung mbH
+
+This code is licensed under MIT license (see LICENSE for details)
+*/
+package actions
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/profiles/latest/resources/mgmt/resources"
+	"github.com/sirupsen/logrus"
+	"github.com/whiteducksoftware/azure-arm-action/pkg/github"
+)
+
+// rollbackPollInterval is the interval in which we check the state of the rollback
+const rollbackPollInterval = 10 * time.Second
+
+// rollbackTimeout is how long we wait for the rollback at most, arm keeps it running if we give up
+const rollbackTimeout = 10 * time.Minute
+
+// onErrorDeployment builds the on error behavior from the onErrorDeployment input
+func onErrorDeployment(options github.Options) *resources.OnErrorDeployment {
+	switch {
+	case len(options.OnErrorDeployment) == 0:
+		return nil
+	case strings.EqualFold(options.OnErrorDeployment, string(resources.OnErrorDeploymentTypeLastSuccessful)):
+		return &resources.OnErrorDeployment{
+			Type: resources.OnErrorDeploymentTypeLastSuccessful,
+		}
+	default:
+		return &resources.OnErrorDeployment{
+			Type:           resources.OnErrorDeploymentTypeSpecificDeployment,
+			DeploymentName: stringPtr(options.OnErrorDeployment),
+		}
+	}
+}
+
+// Rollback describes the on error deployment arm started after the deployment failed
+type Rollback struct {
+	DeploymentName    string
+	ProvisioningState string
+}
+
+// Succeeded checks if the rollback deployment succeeded
+func (rollback Rollback) Succeeded() bool {
+	return strings.EqualFold(rollback.ProvisioningState, string(resources.ProvisioningStateSucceeded))
+}
+
+// waitForRollback waits until arm finished the on error deployment of the failed deployment
+func waitForRollback(ctx context.Context, client resources.DeploymentsClient, options github.Options, deploymentName string) (Rollback, error) {
+	timeout := time.After(rollbackTimeout)
+	for {
+		deployment, err := get(ctx, client, options, deploymentName)
+		if err != nil {
+			return Rollback{}, err
+		}
+
+		if deployment.Properties == nil || deployment.Properties.OnErrorDeployment == nil {
+			return Rollback{}, fmt.Errorf("deployment %s has no on error deployment", deploymentName)
+		}
+
+		onError := deployment.Properties.OnErrorDeployment
+		rollback := Rollback{
+			DeploymentName:    stringValue(onError.DeploymentName),
+			ProvisioningState: stringValue(onError.ProvisioningState),
+		}
+		if len(rollback.DeploymentName) == 0 {
+			rollback.DeploymentName = string(onError.Type)
+		}
+
+		switch resources.ProvisioningState(rollback.ProvisioningState) {
+		case resources.ProvisioningStateSucceeded, resources.ProvisioningStateFailed, resources.ProvisioningStateCanceled:
+			return rollback, nil
+		case "":
+			return rollback, fmt.Errorf("arm reported no state for the rollback to deployment %s", rollback.DeploymentName)
+		}
+
+		logrus.Infof("Waiting for the rollback to deployment %s, state: %s", rollback.DeploymentName, rollback.ProvisioningState)
+		select {
+		case <-ctx.Done():
+			return rollback, ctx.Err()
+		case <-timeout:
+			return rollback, fmt.Errorf("rollback to deployment %s didn't finish within %s, state: %s", rollback.DeploymentName, rollbackTimeout, rollback.ProvisioningState)
+		case <-time.After(rollbackPollInterval):
+		}
+	}
+}
+
+// withRollback adds the result of the rollback to the deployment error
+func withRollback(ctx context.Context, client resources.DeploymentsClient, options github.Options, deploymentName string, deploymentErr error) error {
+	rollback, err := waitForRollback(ctx, client, options, deploymentName)
+	if err != nil {
+		logrus.Warnf("Failed to get the state of the rollback: %s", err)
+		return fmt.Errorf("%w\nthe state of the rollback is unknown: %v", deploymentErr, err)
+	}
+
+	if rollback.Succeeded() {
+		logrus.Infof("Rolled back to deployment %s.", rollback.DeploymentName)
+		return fmt.Errorf("%w\nrolled back to deployment %s", deploymentErr, rollback.DeploymentName)
+	}
+
+	logrus.Errorf("Rollback to deployment %s failed, state: %s", rollback.DeploymentName, rollback.ProvisioningState)
+	return fmt.Errorf("%w\nrollback to deployment %s failed (%s)", deploymentErr, rollback.DeploymentName, rollback.ProvisioningState)
+}
@@ -0,0 +1,70 @@
+package actions
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/whiteducksoftware/azure-arm-action/pkg/github"
+	"github.com/whiteducksoftware/golang-utilities/azure/resources/deployments"
+)
+
+func TestWaitForRollback(t *testing.T) {
+	tests := []struct {
+		name      string
+		state     string
+		wantState string
+		wantErr   string
+	}{
+		{name: "succeeded", state: "Succeeded", wantState: "Succeeded"},
+		{name: "failed", state: "Failed", wantState: "Failed"},
+		{name: "no state", state: "", wantErr: "arm reported no state for the rollback to deployment last-good"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				onError := map[string]interface{}{"type": "SpecificDeployment", "deploymentName": "last-good"}
+				if len(test.state) > 0 {
+					onError["provisioningState"] = test.state
+				}
+
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(map[string]interface{}{
+					"name":       "test",
+					"properties": map[string]interface{}{"provisioningState": "Failed", "onErrorDeployment": onError},
+				})
+			}))
+			defer server.Close()
+
+			options := testOptions(server.URL)
+			options.Scope = github.ScopeResourceGroup
+			options.ResourceGroupName = "my-group"
+			client := deployments.GetClientWithBaseUri(server.URL, testSubscriptionID, autorest.NullAuthorizer{})
+
+			// A state which isn't final would poll, the deadline fails the test instead of hanging
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			rollback, err := waitForRollback(ctx, client, options, "test")
+			if len(test.wantErr) > 0 {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Errorf("Got invalid error, expected %s got %v", test.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Got unexpected error: %s", err)
+			}
+
+			if rollback.DeploymentName != "last-good" || rollback.ProvisioningState != test.wantState {
+				t.Errorf("Got invalid rollback %v", rollback)
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+/*
+Copyright (c) 2020 white duck Gesellschaft für Softwareentwicklung mbH
+
+This code is licensed under MIT license (see LICENSE for details)
+*/
+package actions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/profiles/latest/resources/mgmt/resources"
+	"github.com/sirupsen/logrus"
+	"github.com/whiteducksoftware/azure-arm-action/pkg/github"
+	"github.com/whiteducksoftware/azure-arm-action/pkg/github/io"
+)
+
+// summaryResource is a row of the resources table of the job summary
+type summaryResource struct {
+	Type              string
+	Name              string
+	ProvisioningState string
+}
+
+// deploymentSummary is the report about the deployment which is written to the job summary
+type deploymentSummary struct {
+	DeploymentName string
+	Scope          github.Scope
+	Mode           string
+	Status         string
+	Duration       time.Duration
+	CorrelationID  string
+	Outputs        map[string]Output
+	Resources      []summaryResource
+	Err            error
+}
+
+// writeSummary appends the status, outputs, resources and error of the deployment to the job summary.
+// Nothing is written if the job has no summary file, the resources are left out if the operations can't be listed.
+func writeSummary(ctx context.Context, client resources.DeploymentOperationsClient, options github.Options, deploymentName string, start time.Time, result resources.DeploymentExtended, deployErr error) {
+	if !io.HasSummary() {
+		return
+	}
+
+	summary := deploymentSummary{
+		DeploymentName: deploymentName,
+		Scope:          options.Scope,
+		Mode:           options.DeploymentMode,
+		Duration:       time.Since(start).Round(time.Second),
+		Err:            deployErr,
+	}
+
+	// A skipped deployment reports the last deployment, a what-if only run has no deployment at all
+	switch {
+	case deployErr != nil:
+		summary.Status = string(resources.ProvisioningStateFailed)
+		var deploymentErr *DeploymentError
+		if errors.As(deployErr, &deploymentErr) {
+			summary.CorrelationID = deploymentErr.CorrelationID
+		}
+	case result.Properties == nil:
+		summary.Status = "What-if only"
+	default:
+		summary.Status = string(result.Properties.ProvisioningState)
+		summary.CorrelationID = stringValue(result.Properties.CorrelationID)
+		if result.Name != nil && *result.Name != deploymentName {
+			summary.Status = fmt.Sprintf("Skipped, unchanged since %s", *result.Name)
+			deploymentName = *result.Name
+		}
+
+		outputs, err := ParseOutputs(result.Properties.Outputs)
+		if err != nil {
+			logrus.Warnf("Failed to parse the outputs for the job summary: %s", err)
+		}
+		summary.Outputs = outputs
+	}
+
+	if result.Properties != nil || deployErr != nil {
+		operations, err := listOperations(ctx, client, options, deploymentName)
+		if err != nil {
+			logrus.Debugf("Failed to list the operations for the job summary: %s", err)
+		}
+		summary.Resources = summaryResources(operations)
+	}
+
+	if err := io.AppendSummary(summary.markdown()); err != nil {
+		logrus.Warnf("Failed to write the job summary: %s", err)
+	}
+}
+
+// summaryResources returns the resources of the operations, the last operation of a resource wins
+func summaryResources(operations []resources.DeploymentOperation) []summaryResource {
+	var rows []summaryResource
+	index := make(map[string]int)
+	for _, operation := range operations {
+		properties := operation.Properties
+		if properties == nil || properties.TargetResource == nil {
+			continue
+		}
+
+		row := summaryResource{
+			Type:              stringValue(properties.TargetResource.ResourceType),
+			Name:              stringValue(properties.TargetResource.ResourceName),
+			ProvisioningState: stringValue(properties.ProvisioningState),
+		}
+
+		id := stringValue(properties.TargetResource.ID)
+		if i, ok := index[id]; ok {
+			rows[i] = row
+			continue
+		}
+		index[id] = len(rows)
+		rows = append(rows, row)
+	}
+
+	return rows
+}
+
+// markdown renders the summary, secure outputs are redacted
+func (summary deploymentSummary) markdown() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "## Deployment %s\n\n", summary.DeploymentName)
+	sb.WriteString("| | |\n| --- | --- |\n")
+	fmt.Fprintf(&sb, "| Status | %s |\n", markdownCell(summary.Status))
+	fmt.Fprintf(&sb, "| Scope | %s |\n", summary.Scope)
+	if len(summary.Mode) > 0 {
+		fmt.Fprintf(&sb, "| Mode | %s |\n", markdownCell(summary.Mode))
+	}
+	fmt.Fprintf(&sb, "| Duration | %s |\n", summary.Duration)
+	if len(summary.CorrelationID) > 0 {
+		fmt.Fprintf(&sb, "| Correlation ID | `%s` |\n", summary.CorrelationID)
+	}
+
+	if len(summary.Outputs) > 0 {
+		sb.WriteString("\n### Outputs\n\n| Name | Type | Value |\n| --- | --- | --- |\n")
+		for _, name := range sortedOutputNames(summary.Outputs) {
+			output := summary.Outputs[name]
+			value := output.String()
+			if output.IsSecure() {
+				value = "***"
+			}
+			fmt.Fprintf(&sb, "| %s | %s | %s |\n", markdownCell(name), markdownCell(output.Type), markdownCell(value))
+		}
+	}
+
+	if len(summary.Resources) > 0 {
+		sb.WriteString("\n### Resources\n\n| Type | Name | State |\n| --- | --- | --- |\n")
+		for _, resource := range summary.Resources {
+			fmt.Fprintf(&sb, "| %s | %s | %s |\n", markdownCell(resource.Type), markdownCell(resource.Name), markdownCell(resource.ProvisioningState))
+		}
+	}
+
+	if summary.Err != nil {
+		fmt.Fprintf(&sb, "\n### Error\n\n```text\n%s\n```\n", summary.Err.Error())
+	}
+
+	sb.WriteString("\n")
+	return sb.String()
+}
+
+// markdownCell escapes the value, so it doesn't break the table
+func markdownCell(value string) string {
+	return strings.NewReplacer("|", "\\|", "\r\n", "<br>", "\n", "<br>").Replace(value)
+}
@@ -0,0 +1,50 @@
+package actions
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/profiles/latest/resources/mgmt/resources"
+	"github.com/whiteducksoftware/azure-arm-action/pkg/github"
+)
+
+func TestSummaryMarkdown(t *testing.T) {
+	summary := deploymentSummary{
+		DeploymentName: "app-42",
+		Scope:          github.ScopeResourceGroup,
+		Mode:           "Incremental",
+		Status:         string(resources.ProvisioningStateFailed),
+		Duration:       90 * time.Second,
+		CorrelationID:  "0000-1111",
+		Outputs: map[string]Output{
+			"endpoint": {Type: "String", Value: "https://a|b"},
+			"password": {Type: "SecureString", Value: "secret"},
+		},
+		Resources: summaryResources([]resources.DeploymentOperation{
+			{Properties: &resources.DeploymentOperationProperties{ProvisioningState: stringPtr("Running"), TargetResource: &resources.TargetResource{ID: stringPtr("/sa"), ResourceType: stringPtr("Microsoft.Storage/storageAccounts"), ResourceName: stringPtr("sa")}}},
+			{Properties: &resources.DeploymentOperationProperties{ProvisioningState: stringPtr("Failed"), TargetResource: &resources.TargetResource{ID: stringPtr("/sa"), ResourceType: stringPtr("Microsoft.Storage/storageAccounts"), ResourceName: stringPtr("sa")}}},
+		}),
+		Err: &DeploymentError{Summary: "deployment app-42 failed", Errors: []ErrorDetail{{Code: "Conflict", Message: "name taken"}}},
+	}
+
+	markdown := summary.markdown()
+	for _, want := range []string{
+		"## Deployment app-42\n",
+		"| Status | Failed |\n",
+		"| Duration | 1m30s |\n",
+		"| Correlation ID | `0000-1111` |\n",
+		"| endpoint | String | https://a\\|b |\n",
+		"| password | SecureString | *** |\n",
+		"| Microsoft.Storage/storageAccounts | sa | Failed |\n",
+		"```text\ndeployment app-42 failed\n  - Conflict: name taken\n```\n",
+	} {
+		if !strings.Contains(markdown, want) {
+			t.Errorf("Summary is missing %q:\n%s", want, markdown)
+		}
+	}
+
+	if strings.Contains(markdown, "secret") || strings.Contains(markdown, "| Running |") {
+		t.Errorf("Summary contains a secure value or an outdated state:\n%s", markdown)
+	}
+}
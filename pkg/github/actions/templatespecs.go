@@ -0,0 +1,135 @@
+/*
+Copyright (c) 2020 white duck Gesellschaft für Softwareentwicklung mbH
+
+This code is licensed under MIT license (see LICENSE for details)
+*/
+package actions
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/profiles/latest/resources/mgmt/resources"
+	"github.com/Azure/azure-sdk-for-go/profiles/preview/preview/resources/mgmt/templatespecs"
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/sirupsen/logrus"
+	"github.com/whiteducksoftware/azure-arm-action/pkg/github"
+)
+
+var templateSpecIDPattern = regexp.MustCompile(`(?i)^/subscriptions/([^/]+)/resourceGroups/([^/]+)/providers/Microsoft\.Resources/templateSpecs/([^/]+)/versions/([^/]+)$`)
+
+// TemplateSpecVersion identifies a single version of a template spec
+type TemplateSpecVersion struct {
+	SubscriptionID    string
+	ResourceGroupName string
+	Name              string
+	Version           string
+}
+
+// ID returns the resource id of the template spec version
+func (spec TemplateSpecVersion) ID() string {
+	return fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Resources/templateSpecs/%s/versions/%s", spec.SubscriptionID, spec.ResourceGroupName, spec.Name, spec.Version)
+}
+
+// ParseTemplateSpecID takes the templateSpecId input, which is either the full resource id
+// of the template spec version or NAME:VERSION, and resolves it to the template spec version
+func ParseTemplateSpecID(options github.Options) (TemplateSpecVersion, error) {
+	id := strings.TrimSpace(options.TemplateSpecID)
+	if match := templateSpecIDPattern.FindStringSubmatch(id); match != nil {
+		return TemplateSpecVersion{
+			SubscriptionID:    match[1],
+			ResourceGroupName: match[2],
+			Name:              match[3],
+			Version:           match[4],
+		}, nil
+	}
+
+	nameVersion := strings.SplitN(id, ":", 2)
+	if len(nameVersion) != 2 || len(nameVersion[0]) == 0 || len(nameVersion[1]) == 0 || strings.Contains(id, "/") {
+		return TemplateSpecVersion{}, fmt.Errorf("invalid template spec id %s, expected a resource id or NAME:VERSION", id)
+	}
+
+	resourceGroupName := options.TemplateSpecResourceGroup
+	if len(resourceGroupName) == 0 {
+		resourceGroupName = options.ResourceGroupName
+	}
+	if len(resourceGroupName) == 0 {
+		return TemplateSpecVersion{}, fmt.Errorf("the resource group of the template spec %s is unknown, set templateSpecResourceGroup", id)
+	}
+
+	return TemplateSpecVersion{
+		SubscriptionID:    options.Credentials.SubscriptionID,
+		ResourceGroupName: resourceGroupName,
+		Name:              nameVersion[0],
+		Version:           nameVersion[1],
+	}, nil
+}
+
+// PublishTemplateSpec publishes the template as a new version of the template spec,
+// the template spec itself is created in the location of its resource group if it doesn't exist yet
+func PublishTemplateSpec(ctx context.Context, options github.Options, authorizer autorest.Authorizer) (string, error) {
+	if len(options.TemplateLink) > 0 || options.Template == nil {
+		return "", fmt.Errorf("publishing a template spec requires a local template")
+	}
+
+	spec, err := ParseTemplateSpecID(options)
+	if err != nil {
+		return "", err
+	}
+
+	specsClient := templatespecs.NewClientWithBaseURI(options.Credentials.ARMEndpointURL, spec.SubscriptionID)
+	specsClient.Authorizer = authorizer
+	versionsClient := templatespecs.NewVersionsClientWithBaseURI(options.Credentials.ARMEndpointURL, spec.SubscriptionID)
+	versionsClient.Authorizer = authorizer
+
+	// ARM would overwrite an existing version, refuse that so published versions stay as they are
+	version, err := versionsClient.Get(ctx, spec.ResourceGroupName, spec.Name, spec.Version)
+	if err == nil {
+		return "", fmt.Errorf("version %s of template spec %s already exists", spec.Version, spec.Name)
+	} else if version.StatusCode != http.StatusNotFound {
+		return "", fmt.Errorf("cannot get template spec version: %v", err)
+	}
+
+	// Create the template spec if it doesn't exist yet
+	templateSpec, err := specsClient.Get(ctx, spec.ResourceGroupName, spec.Name, "")
+	if err != nil {
+		if templateSpec.StatusCode != http.StatusNotFound {
+			return "", fmt.Errorf("cannot get template spec: %v", err)
+		}
+
+		groupsClient := resources.NewGroupsClientWithBaseURI(options.Credentials.ARMEndpointURL, spec.SubscriptionID)
+		groupsClient.Authorizer = authorizer
+		group, err := groupsClient.Get(ctx, spec.ResourceGroupName)
+		if err != nil {
+			return "", fmt.Errorf("cannot get resource group of the template spec: %v", err)
+		}
+
+		logrus.Infof("Creating template spec %s in %s", spec.Name, *group.Location)
+		templateSpec, err = specsClient.CreateOrUpdate(ctx, spec.ResourceGroupName, spec.Name, templatespecs.TemplateSpec{
+			Location: group.Location,
+		})
+		if err != nil {
+			return "", fmt.Errorf("cannot create template spec: %v", err)
+		}
+	}
+
+	logrus.Infof("Publishing version %s of template spec %s", spec.Version, spec.Name)
+	version, err = versionsClient.CreateOrUpdate(ctx, spec.ResourceGroupName, spec.Name, spec.Version, templatespecs.VersionTemplatespecs{
+		Location: templateSpec.Location,
+		VersionProperties: &templatespecs.VersionProperties{
+			Template: options.Template,
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("cannot create template spec version: %v", err)
+	}
+
+	if version.ID != nil {
+		return *version.ID, nil
+	}
+
+	return spec.ID(), nil
+}
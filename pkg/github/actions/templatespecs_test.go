@@ -0,0 +1,132 @@
+package actions
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/whiteducksoftware/azure-arm-action/pkg/github"
+	"github.com/whiteducksoftware/golang-utilities/azure/auth"
+)
+
+func TestParseTemplateSpecID(t *testing.T) {
+	versionID := "/subscriptions/11111111-1111-1111-1111-111111111111/resourceGroups/specs/providers/Microsoft.Resources/templateSpecs/app/versions/1.0"
+
+	tests := []struct {
+		name          string
+		id            string
+		specGroup     string
+		resourceGroup string
+		want          TemplateSpecVersion
+		wantErr       bool
+	}{
+		{name: "resource id", id: versionID, resourceGroup: "my-group", want: TemplateSpecVersion{SubscriptionID: "11111111-1111-1111-1111-111111111111", ResourceGroupName: "specs", Name: "app", Version: "1.0"}},
+		{name: "resource id case-insensitive", id: " /SUBSCRIPTIONS/1/RESOURCEGROUPS/specs/providers/microsoft.resources/TEMPLATESPECS/app/VERSIONS/2 ", want: TemplateSpecVersion{SubscriptionID: "1", ResourceGroupName: "specs", Name: "app", Version: "2"}},
+		{name: "name and version in template spec group", id: "app:1.0", specGroup: "specs", resourceGroup: "my-group", want: TemplateSpecVersion{SubscriptionID: testSubscriptionID, ResourceGroupName: "specs", Name: "app", Version: "1.0"}},
+		{name: "name and version in resource group", id: "app:1.0", resourceGroup: "my-group", want: TemplateSpecVersion{SubscriptionID: testSubscriptionID, ResourceGroupName: "my-group", Name: "app", Version: "1.0"}},
+		{name: "version with colon", id: "app:1.0:beta", resourceGroup: "my-group", want: TemplateSpecVersion{SubscriptionID: testSubscriptionID, ResourceGroupName: "my-group", Name: "app", Version: "1.0:beta"}},
+		{name: "no resource group", id: "app:1.0", wantErr: true},
+		{name: "no version", id: "app", resourceGroup: "my-group", wantErr: true},
+		{name: "empty name", id: ":1.0", resourceGroup: "my-group", wantErr: true},
+		{name: "empty version", id: "app:", resourceGroup: "my-group", wantErr: true},
+		{name: "template spec without version", id: "/subscriptions/1/resourceGroups/specs/providers/Microsoft.Resources/templateSpecs/app", resourceGroup: "my-group", wantErr: true},
+		{name: "empty", id: "", resourceGroup: "my-group", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			options := github.Options{Inputs: github.Inputs{
+				Credentials:               &auth.SDKAuth{SubscriptionID: testSubscriptionID},
+				TemplateSpecID:            test.id,
+				TemplateSpecResourceGroup: test.specGroup,
+				ResourceGroupName:         test.resourceGroup,
+			}}
+
+			spec, err := ParseTemplateSpecID(options)
+			if test.wantErr {
+				if err == nil {
+					t.Errorf("Got no error for %s, got %v", test.id, spec)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Got unexpected error: %s", err)
+			}
+
+			if spec != test.want {
+				t.Errorf("Got invalid template spec version, expected %v got %v", test.want, spec)
+			}
+		})
+	}
+
+	spec := TemplateSpecVersion{SubscriptionID: "11111111-1111-1111-1111-111111111111", ResourceGroupName: "specs", Name: "app", Version: "1.0"}
+	if spec.ID() != versionID {
+		t.Errorf("Got invalid id, expected %s got %s", versionID, spec.ID())
+	}
+}
+
+func TestPublishTemplateSpec(t *testing.T) {
+	specPath := "/subscriptions/" + testSubscriptionID + "/resourceGroups/specs/providers/Microsoft.Resources/templateSpecs/app"
+
+	tests := []struct {
+		name          string
+		specExists    bool
+		versionExists bool
+		wantWrites    []string
+		wantErr       bool
+	}{
+		{name: "new template spec", wantWrites: []string{specPath, specPath + "/versions/1.0"}},
+		{name: "new version", specExists: true, wantWrites: []string{specPath + "/versions/1.0"}},
+		{name: "existing version", specExists: true, versionExists: true, wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stand := newARMStandIn(t)
+			stand.respond = func(request armRequest) (int, interface{}) {
+				switch {
+				case request.Method == http.MethodGet && request.Path == specPath && !test.specExists,
+					request.Method == http.MethodGet && request.Path == specPath+"/versions/1.0" && !test.versionExists:
+					return http.StatusNotFound, map[string]interface{}{"error": map[string]interface{}{"code": "ResourceNotFound"}}
+				case strings.EqualFold(request.Path, "/subscriptions/"+testSubscriptionID+"/resourcegroups/specs"), request.Path == specPath:
+					return http.StatusOK, map[string]interface{}{"id": request.Path, "location": "westeurope"}
+				}
+				return 0, nil
+			}
+
+			options := testOptions(stand.URL)
+			options.TemplateSpecID = "app:1.0"
+			options.TemplateSpecResourceGroup = "specs"
+
+			id, err := PublishTemplateSpec(context.Background(), options, autorest.NullAuthorizer{})
+			if test.wantErr {
+				if err == nil {
+					t.Errorf("Got no error for an existing version")
+				}
+			} else if err != nil {
+				t.Fatalf("Got unexpected error: %s", err)
+			} else if want := specPath + "/versions/1.0"; id != want {
+				t.Errorf("Got invalid id, expected %s got %s", want, id)
+			}
+
+			writes := stand.writes()
+			if len(writes) != len(test.wantWrites) {
+				t.Fatalf("Got invalid requests, expected %v got %v", test.wantWrites, writes)
+			}
+			for i, write := range writes {
+				if write.Method != http.MethodPut || write.Path != test.wantWrites[i] {
+					t.Errorf("Got invalid request, expected PUT %s got %s %s", test.wantWrites[i], write.Method, write.Path)
+				}
+			}
+			if len(writes) > 0 {
+				version := writes[len(writes)-1].Body
+				properties, _ := version["properties"].(map[string]interface{})
+				if version["location"] != "westeurope" || properties["template"] == nil {
+					t.Errorf("Got invalid template spec version: %v", version)
+				}
+			}
+		})
+	}
+}
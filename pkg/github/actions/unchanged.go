@@ -0,0 +1,118 @@
+/*
+Copyright (c) 2020 white duck Gesellschaft für Softwareentwicklung mbH
+
+This code is licensed under MIT license (see LICENSE for details)
+*/
+package actions
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/Azure/azure-sdk-for-go/profiles/latest/resources/mgmt/resources"
+	"github.com/whiteducksoftware/azure-arm-action/pkg/github"
+)
+
+// Tags which are added to every deployment, to find the last deployment of the same template
+const (
+	nameTag = "azure-arm-action-name"
+	hashTag = "azure-arm-action-hash"
+)
+
+// succeededFilter only lists successful deployments
+const succeededFilter = "provisioningState eq 'Succeeded'"
+
+// deploymentHash computes a hash of the template and parameters of the deployment,
+// remote templates, template spec versions and parameters can change without the link changing, so they can't be hashed
+func deploymentHash(options github.Options, properties *resources.DeploymentProperties) (string, bool) {
+	if properties.ParametersLink != nil || properties.TemplateLink != nil {
+		return "", false
+	}
+
+	// json.Marshal sorts the keys of maps, so the hash is stable
+	data, err := json.Marshal(struct {
+		Scope      github.Scope             `json:"scope"`
+		Mode       resources.DeploymentMode `json:"mode"`
+		Template   interface{}              `json:"template,omitempty"`
+		Parameters interface{}              `json:"parameters"`
+	}{
+		Scope:      options.Scope,
+		Mode:       properties.Mode,
+		Template:   properties.Template,
+		Parameters: properties.Parameters,
+	})
+	if err != nil {
+		return "", false
+	}
+
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:]), true
+}
+
+// deploymentTags returns the tags which identify the deployment and its content
+func deploymentTags(options github.Options, hash string) map[string]*string {
+	tags := map[string]*string{
+		nameTag: stringPtr(options.DeploymentName),
+	}
+	if len(hash) > 0 {
+		tags[hashTag] = stringPtr(hash)
+	}
+
+	return tags
+}
+
+// lastSuccessfulDeployment looks up the most recent successful deployment with the same base name at the scope
+func lastSuccessfulDeployment(ctx context.Context, client resources.DeploymentsClient, options github.Options) (*resources.DeploymentExtended, error) {
+	var err error
+	var iterator resources.DeploymentListResultIterator
+	switch options.Scope {
+	case github.ScopeResourceGroup:
+		iterator, err = client.ListByResourceGroupComplete(ctx, options.ResourceGroupName, succeededFilter, nil)
+	case github.ScopeManagementGroup:
+		iterator, err = client.ListAtManagementGroupScopeComplete(ctx, options.ManagementGroupId, succeededFilter, nil)
+	case github.ScopeTenant:
+		iterator, err = client.ListAtTenantScopeComplete(ctx, succeededFilter, nil)
+	default:
+		iterator, err = client.ListAtSubscriptionScopeComplete(ctx, succeededFilter, nil)
+	}
+
+	var last *resources.DeploymentExtended
+	for ; err == nil && iterator.NotDone(); err = iterator.NextWithContext(ctx) {
+		deployment := iterator.Value()
+		if deployment.Properties == nil || deployment.Properties.Timestamp == nil || deployment.Properties.ProvisioningState != resources.ProvisioningStateSucceeded {
+			continue
+		}
+
+		name, ok := deployment.Tags[nameTag]
+		if !ok || name == nil || *name != options.DeploymentName {
+			continue
+		}
+
+		if last == nil || deployment.Properties.Timestamp.After(last.Properties.Timestamp.Time) {
+			last = &deployment
+		}
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("cannot list deployments: %v", err)
+	}
+
+	return last, nil
+}
+
+// unchangedDeployment returns the last successful deployment if its hash matches
+func unchangedDeployment(ctx context.Context, client resources.DeploymentsClient, options github.Options, hash string) (*resources.DeploymentExtended, error) {
+	last, err := lastSuccessfulDeployment(ctx, client, options)
+	if err != nil || last == nil {
+		return nil, err
+	}
+
+	if lastHash, ok := last.Tags[hashTag]; ok && lastHash != nil && *lastHash == hash {
+		return last, nil
+	}
+
+	return nil, nil
+}
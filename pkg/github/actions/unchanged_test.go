@@ -0,0 +1,135 @@
+package actions
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/profiles/latest/resources/mgmt/resources"
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/whiteducksoftware/azure-arm-action/pkg/github"
+	"github.com/whiteducksoftware/golang-utilities/azure/resources/deployments"
+)
+
+func TestDeploymentHash(t *testing.T) {
+	decode := func(data string) interface{} {
+		var v interface{}
+		if err := json.Unmarshal([]byte(data), &v); err != nil {
+			t.Fatalf("Got unexpected error: %s", err)
+		}
+		return v
+	}
+
+	options := testOptions("")
+	properties := func(template, parameters string) *resources.DeploymentProperties {
+		return &resources.DeploymentProperties{Mode: resources.DeploymentMode("Incremental"), Template: decode(template), Parameters: decode(parameters)}
+	}
+
+	hash, hashable := deploymentHash(options, properties(`{"a": 1, "b": {"c": 2, "d": 3}}`, `{"name": {"value": "app"}, "sku": {"value": "S1"}}`))
+	if !hashable || len(hash) == 0 {
+		t.Fatalf("Got no hash for an inline template")
+	}
+
+	// The order of keys must not change the hash
+	if reordered, _ := deploymentHash(options, properties(`{"b": {"d": 3, "c": 2}, "a": 1}`, `{"sku": {"value": "S1"}, "name": {"value": "app"}}`)); reordered != hash {
+		t.Errorf("Got different hash for reordered keys, expected %s got %s", hash, reordered)
+	}
+
+	if changed, _ := deploymentHash(options, properties(`{"a": 1, "b": {"c": 2, "d": 3}}`, `{"name": {"value": "other"}, "sku": {"value": "S1"}}`)); changed == hash {
+		t.Errorf("Got the same hash for changed parameters")
+	}
+
+	options.Scope = github.ScopeSubscription
+	if scoped, _ := deploymentHash(options, properties(`{"a": 1, "b": {"c": 2, "d": 3}}`, `{"name": {"value": "app"}, "sku": {"value": "S1"}}`)); scoped == hash {
+		t.Errorf("Got the same hash for a different scope")
+	}
+
+	linked := []*resources.DeploymentProperties{
+		{TemplateLink: &resources.TemplateLink{URI: stringPtr("https://example.com/template.json")}},
+		// Published template spec versions can be overwritten
+		{TemplateLink: &resources.TemplateLink{ID: stringPtr("/templateSpecs/app/versions/1")}},
+		{Template: decode(`{}`), ParametersLink: &resources.ParametersLink{URI: stringPtr("https://example.com/parameters.json")}},
+	}
+	for _, properties := range linked {
+		if _, hashable := deploymentHash(options, properties); hashable {
+			t.Errorf("Got hash for linked deployment %v", properties)
+		}
+	}
+}
+
+func TestDeploymentTags(t *testing.T) {
+	options := testOptions("")
+
+	tags := deploymentTags(options, "abc")
+	if len(tags) != 2 || *tags[nameTag] != "test" || *tags[hashTag] != "abc" {
+		t.Errorf("Got invalid tags %v", tags)
+	}
+
+	if tags := deploymentTags(options, ""); len(tags) != 1 || *tags[nameTag] != "test" {
+		t.Errorf("Got invalid tags without hash %v", tags)
+	}
+}
+
+func TestUnchangedDeployment(t *testing.T) {
+	deployment := func(name, timestamp string, tags map[string]string) map[string]interface{} {
+		return map[string]interface{}{
+			"id":   "/subscriptions/" + testSubscriptionID + "/resourcegroups/my-group/providers/Microsoft.Resources/deployments/" + name,
+			"name": name,
+			"tags": tags,
+			"properties": map[string]interface{}{
+				"provisioningState": "Succeeded",
+				"timestamp":         timestamp,
+			},
+		}
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if want := "/subscriptions/" + testSubscriptionID + "/resourcegroups/my-group/providers/Microsoft.Resources/deployments/"; !strings.EqualFold(r.URL.Path, want) {
+			t.Errorf("Got invalid path, expected %s got %s", want, r.URL.Path)
+		}
+		if filter := r.URL.Query().Get("$filter"); filter != succeededFilter {
+			t.Errorf("Got invalid filter, expected %s got %s", succeededFilter, filter)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"value": []interface{}{
+				deployment("test-1", "2020-01-01T00:00:00Z", map[string]string{nameTag: "test", hashTag: "abc"}),
+				deployment("test-2", "2020-02-01T00:00:00Z", map[string]string{nameTag: "test", hashTag: "def"}),
+				deployment("other-1", "2020-03-01T00:00:00Z", map[string]string{nameTag: "other", hashTag: "def"}),
+				deployment("untagged", "2020-04-01T00:00:00Z", nil),
+			},
+		})
+	}))
+	defer server.Close()
+
+	options := testOptions(server.URL)
+	options.Scope = github.ScopeResourceGroup
+	options.ResourceGroupName = "my-group"
+	client := deployments.GetClientWithBaseUri(server.URL, testSubscriptionID, autorest.NullAuthorizer{})
+
+	last, err := lastSuccessfulDeployment(context.Background(), client, options)
+	if err != nil {
+		t.Fatalf("Got unexpected error: %s", err)
+	}
+	if last == nil || *last.Name != "test-2" {
+		t.Fatalf("Got invalid last deployment, expected test-2 got %v", last)
+	}
+
+	if unchanged, err := unchangedDeployment(context.Background(), client, options, "def"); err != nil || unchanged == nil || *unchanged.Name != "test-2" {
+		t.Errorf("Got invalid unchanged deployment for the same hash: %v, %v", unchanged, err)
+	}
+
+	// Only the last deployment counts, older deployments with the same hash are outdated
+	if unchanged, err := unchangedDeployment(context.Background(), client, options, "abc"); err != nil || unchanged != nil {
+		t.Errorf("Got unchanged deployment for the hash of an older deployment: %v, %v", unchanged, err)
+	}
+
+	options.DeploymentName = "missing"
+	if last, err := lastSuccessfulDeployment(context.Background(), client, options); err != nil || last != nil {
+		t.Errorf("Got deployment for an unknown name: %v, %v", last, err)
+	}
+}
@@ -0,0 +1,203 @@
+/*
+Copyright (c) 2020 white duck Gesellschaft für Softwareentwicklung mbH
+
+This code is licensed under MIT license (see LICENSE for details)
+*/
+package actions
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/profiles/latest/resources/mgmt/resources"
+	"github.com/sirupsen/logrus"
+	"github.com/whiteducksoftware/azure-arm-action/pkg/github"
+)
+
+// changeSymbols maps the what-if change types to the symbols used in the printed diff
+var changeSymbols = map[resources.ChangeType]string{
+	resources.ChangeTypeCreate:   "+",
+	resources.ChangeTypeDelete:   "-",
+	resources.ChangeTypeModify:   "~",
+	resources.ChangeTypeDeploy:   "!",
+	resources.ChangeTypeNoChange: "=",
+	resources.ChangeTypeIgnore:   "*",
+}
+
+// changeOrder defines in which order the resource changes are printed
+var changeOrder = []resources.ChangeType{
+	resources.ChangeTypeDelete,
+	resources.ChangeTypeCreate,
+	resources.ChangeTypeModify,
+	resources.ChangeTypeDeploy,
+	resources.ChangeTypeNoChange,
+	resources.ChangeTypeIgnore,
+}
+
+// propertyChangeSymbols maps the what-if property change types to the symbols used in the printed diff
+var propertyChangeSymbols = map[resources.PropertyChangeType]string{
+	resources.PropertyChangeTypeCreate: "+",
+	resources.PropertyChangeTypeDelete: "-",
+	resources.PropertyChangeTypeModify: "~",
+	resources.PropertyChangeTypeArray:  "~",
+}
+
+// WhatIf runs the arm what-if operation for the deployment and
+// returns the predicted changes without creating the deployment
+func WhatIf(ctx context.Context, client resources.DeploymentsClient, options github.Options, deploymentName string, deployment *resources.DeploymentProperties) (resources.WhatIfOperationResult, error) {
+	properties := &resources.DeploymentWhatIfProperties{
+		Template:       deployment.Template,
+		TemplateLink:   deployment.TemplateLink,
+		Parameters:     deployment.Parameters,
+		ParametersLink: deployment.ParametersLink,
+		Mode:           deployment.Mode,
+	}
+
+	var err error
+	var result resources.WhatIfOperationResult
+	location := deploymentLocation(options)
+	switch options.Scope {
+	case github.ScopeResourceGroup:
+		var future resources.DeploymentsWhatIfFuture
+		future, err = client.WhatIf(ctx, options.ResourceGroupName, deploymentName, resources.DeploymentWhatIf{Properties: properties})
+		if err == nil {
+			err = future.WaitForCompletionRef(ctx, client.Client)
+		}
+		if err == nil {
+			result, err = future.Result(client)
+		}
+	case github.ScopeManagementGroup:
+		var future resources.DeploymentsWhatIfAtManagementGroupScopeFuture
+		future, err = client.WhatIfAtManagementGroupScope(ctx, options.ManagementGroupId, deploymentName, resources.ScopedDeploymentWhatIf{Location: location, Properties: properties})
+		if err == nil {
+			err = future.WaitForCompletionRef(ctx, client.Client)
+		}
+		if err == nil {
+			result, err = future.Result(client)
+		}
+	case github.ScopeTenant:
+		var future resources.DeploymentsWhatIfAtTenantScopeFuture
+		future, err = client.WhatIfAtTenantScope(ctx, deploymentName, resources.ScopedDeploymentWhatIf{Location: location, Properties: properties})
+		if err == nil {
+			err = future.WaitForCompletionRef(ctx, client.Client)
+		}
+		if err == nil {
+			result, err = future.Result(client)
+		}
+	default:
+		var future resources.DeploymentsWhatIfAtSubscriptionScopeFuture
+		future, err = client.WhatIfAtSubscriptionScope(ctx, deploymentName, resources.DeploymentWhatIf{Location: location, Properties: properties})
+		if err == nil {
+			err = future.WaitForCompletionRef(ctx, client.Client)
+		}
+		if err == nil {
+			result, err = future.Result(client)
+		}
+	}
+
+	if err != nil {
+		if deploymentErr := newServiceDeploymentError(fmt.Sprintf("what-if of deployment %s failed", deploymentName), err); deploymentErr != nil {
+			return resources.WhatIfOperationResult{}, deploymentErr
+		}
+		return resources.WhatIfOperationResult{}, fmt.Errorf("cannot run what-if operation: %v", err)
+	}
+
+	if result.Error != nil {
+		return resources.WhatIfOperationResult{}, newDeploymentError(fmt.Sprintf("what-if of deployment %s failed", deploymentName), result.Error)
+	}
+
+	return result, nil
+}
+
+// FormatWhatIfResult renders the predicted changes of a what-if operation as a readable diff
+func FormatWhatIfResult(result resources.WhatIfOperationResult) string {
+	var changes []resources.WhatIfChange
+	if result.WhatIfOperationProperties != nil && result.Changes != nil {
+		changes = *result.Changes
+	}
+
+	// group the changes by their type
+	grouped := make(map[resources.ChangeType][]resources.WhatIfChange)
+	for _, change := range changes {
+		grouped[change.ChangeType] = append(grouped[change.ChangeType], change)
+	}
+
+	var sb strings.Builder
+	for _, changeType := range changeOrder {
+		group := grouped[changeType]
+		sort.Slice(group, func(i, j int) bool {
+			return stringValue(group[i].ResourceID) < stringValue(group[j].ResourceID)
+		})
+
+		for _, change := range group {
+			fmt.Fprintf(&sb, "%s %-8s %s\n", changeSymbols[changeType], changeType, stringValue(change.ResourceID))
+			if change.Delta != nil {
+				writePropertyChanges(&sb, *change.Delta, 1)
+			}
+		}
+	}
+
+	fmt.Fprintf(&sb, "Resource changes: %d to create, %d to modify, %d to delete, %d to deploy, %d no change, %d to ignore.",
+		len(grouped[resources.ChangeTypeCreate]),
+		len(grouped[resources.ChangeTypeModify]),
+		len(grouped[resources.ChangeTypeDelete]),
+		len(grouped[resources.ChangeTypeDeploy]),
+		len(grouped[resources.ChangeTypeNoChange]),
+		len(grouped[resources.ChangeTypeIgnore]))
+
+	return sb.String()
+}
+
+func writePropertyChanges(sb *strings.Builder, changes []resources.WhatIfPropertyChange, depth int) {
+	indent := strings.Repeat("    ", depth)
+	for _, change := range changes {
+		symbol := propertyChangeSymbols[change.PropertyChangeType]
+		path := stringValue(change.Path)
+
+		switch change.PropertyChangeType {
+		case resources.PropertyChangeTypeCreate:
+			fmt.Fprintf(sb, "%s%s %s: %s\n", indent, symbol, path, formatValue(change.After))
+		case resources.PropertyChangeTypeDelete:
+			fmt.Fprintf(sb, "%s%s %s: %s\n", indent, symbol, path, formatValue(change.Before))
+		case resources.PropertyChangeTypeArray:
+			fmt.Fprintf(sb, "%s%s %s:\n", indent, symbol, path)
+		default:
+			fmt.Fprintf(sb, "%s%s %s: %s => %s\n", indent, symbol, path, formatValue(change.Before), formatValue(change.After))
+		}
+
+		if change.Children != nil {
+			writePropertyChanges(sb, *change.Children, depth+1)
+		}
+	}
+}
+
+func formatValue(value interface{}) string {
+	if value == nil {
+		return "null"
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+
+	return string(data)
+}
+
+func stringValue(value *string) string {
+	if value == nil {
+		return ""
+	}
+
+	return *value
+}
+
+// printWhatIfResult writes the predicted changes line by line to the log
+func printWhatIfResult(result resources.WhatIfOperationResult) {
+	for _, line := range strings.Split(FormatWhatIfResult(result), "\n") {
+		logrus.Info(line)
+	}
+}
@@ -0,0 +1,118 @@
+package actions
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/profiles/latest/resources/mgmt/resources"
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/whiteducksoftware/azure-arm-action/pkg/github"
+)
+
+func TestFormatWhatIfResult(t *testing.T) {
+	storage := "/subscriptions/1/resourceGroups/rg/providers/Microsoft.Storage/storageAccounts/st"
+	vnet := "/subscriptions/1/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet"
+
+	tests := []struct {
+		name    string
+		changes []resources.WhatIfChange
+		want    []string
+	}{
+		{
+			name:    "no changes",
+			changes: nil,
+			want:    []string{"Resource changes: 0 to create, 0 to modify, 0 to delete, 0 to deploy, 0 no change, 0 to ignore."},
+		},
+		{
+			name: "create",
+			changes: []resources.WhatIfChange{{
+				ResourceID: stringPtr(storage),
+				ChangeType: resources.ChangeTypeCreate,
+				Delta: &[]resources.WhatIfPropertyChange{
+					{Path: stringPtr("sku.name"), PropertyChangeType: resources.PropertyChangeTypeCreate, After: "Standard_LRS"},
+				},
+			}},
+			want: []string{
+				"+ Create   " + storage,
+				"    + sku.name: \"Standard_LRS\"",
+				"Resource changes: 1 to create, 0 to modify, 0 to delete, 0 to deploy, 0 no change, 0 to ignore.",
+			},
+		},
+		{
+			name: "modify",
+			changes: []resources.WhatIfChange{{
+				ResourceID: stringPtr(vnet),
+				ChangeType: resources.ChangeTypeModify,
+				Delta: &[]resources.WhatIfPropertyChange{
+					{Path: stringPtr("properties.enableDdosProtection"), PropertyChangeType: resources.PropertyChangeTypeModify, Before: false, After: true},
+					{Path: stringPtr("tags.owner"), PropertyChangeType: resources.PropertyChangeTypeDelete, Before: "team"},
+					{Path: stringPtr("properties.addressSpace.addressPrefixes"), PropertyChangeType: resources.PropertyChangeTypeArray, Children: &[]resources.WhatIfPropertyChange{
+						{Path: stringPtr("0"), PropertyChangeType: resources.PropertyChangeTypeCreate, After: "10.1.0.0/16"},
+					}},
+				},
+			}},
+			want: []string{
+				"~ Modify   " + vnet,
+				"    ~ properties.enableDdosProtection: false => true",
+				"    - tags.owner: \"team\"",
+				"    ~ properties.addressSpace.addressPrefixes:",
+				"        + 0: \"10.1.0.0/16\"",
+				"Resource changes: 0 to create, 1 to modify, 0 to delete, 0 to deploy, 0 no change, 0 to ignore.",
+			},
+		},
+		{
+			name: "delete, no change and ignore in order",
+			changes: []resources.WhatIfChange{
+				{ResourceID: stringPtr(vnet), ChangeType: resources.ChangeTypeIgnore},
+				{ResourceID: stringPtr(storage), ChangeType: resources.ChangeTypeNoChange},
+				{ResourceID: stringPtr(vnet), ChangeType: resources.ChangeTypeDelete},
+			},
+			want: []string{
+				"- Delete   " + vnet,
+				"= NoChange " + storage,
+				"* Ignore   " + vnet,
+				"Resource changes: 0 to create, 0 to modify, 1 to delete, 0 to deploy, 1 no change, 1 to ignore.",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := resources.WhatIfOperationResult{WhatIfOperationProperties: &resources.WhatIfOperationProperties{Changes: &test.changes}}
+			if got, want := FormatWhatIfResult(result), strings.Join(test.want, "\n"); got != want {
+				t.Errorf("Got invalid what-if result, expected\n%s\ngot\n%s", want, got)
+			}
+		})
+	}
+}
+
+func TestDeployWhatIfOnly(t *testing.T) {
+	stand := newARMStandIn(t)
+	options := testOptions(stand.URL)
+	options.Scope = github.ScopeResourceGroup
+	options.ResourceGroupName = "my-group"
+	options.WhatIf = true
+
+	result, err := Deploy(context.Background(), options, autorest.NullAuthorizer{})
+	if err != nil {
+		t.Fatalf("Deploy failed: %s", err)
+	}
+
+	writes := stand.writes()
+	if len(writes) != 2 {
+		t.Fatalf("Got invalid count of requests, expected 2 (validate, what-if) got %d", len(writes))
+	}
+	if writes[0].Method != http.MethodPost || !strings.HasSuffix(writes[0].Path, "/validate") {
+		t.Errorf("Got invalid validate request %s %s", writes[0].Method, writes[0].Path)
+	}
+	if writes[1].Method != http.MethodPost || !strings.HasSuffix(writes[1].Path, "/whatIf") {
+		t.Errorf("Got invalid what-if request %s %s", writes[1].Method, writes[1].Path)
+	}
+
+	// A what-if only run has no deployment, so there are no outputs
+	if result.Name == nil || result.Properties != nil {
+		t.Errorf("Got invalid result for a what-if only run %v", result)
+	}
+}
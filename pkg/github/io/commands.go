@@ -0,0 +1,33 @@
+/*
+Copyright (c) 2020 white duck Gesellschaft für Softwareentwicklung mbH
+
+This code is licensed under MIT license (see LICENSE for details)
+*/
+
+// Package io contains the github actions workflow commands and environment files
+package io
+
+import (
+	"fmt"
+	"strings"
+)
+
+// StartGroup starts a collapsible group in the github actions log, groups can't be nested
+func StartGroup(title string) {
+	fmt.Printf("::group::%s\n", title)
+}
+
+// EndGroup ends the current group in the github actions log
+func EndGroup() {
+	fmt.Println("::endgroup::")
+}
+
+// AddMask registers the value as secret, github replaces it with *** in the rest of the log.
+// Every line is masked on its own, as github doesn't match masks across lines.
+func AddMask(value string) {
+	for _, line := range strings.Split(value, "\n") {
+		if line = strings.TrimSpace(line); len(line) > 0 {
+			fmt.Printf("::add-mask::%s\n", escapeData(line))
+		}
+	}
+}
@@ -0,0 +1,88 @@
+/*
+Copyright (c) 2020 white duck Gesellschaft für Softwareentwicklung mbH
+
+This code is licensed under MIT license (see LICENSE for details)
+*/
+package io
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Environment variables which point to the files for the step outputs and the environment of the following steps
+const (
+	outputFile  = "GITHUB_OUTPUT"
+	envFile     = "GITHUB_ENV"
+	summaryFile = "GITHUB_STEP_SUMMARY"
+)
+
+// SetOutput writes the output of the step to the $GITHUB_OUTPUT file,
+// the deprecated set-output command is used if the file isn't available
+func SetOutput(name, value string) error {
+	if path := os.Getenv(outputFile); len(path) > 0 {
+		return appendKeyValue(path, name, value)
+	}
+
+	fmt.Printf("::set-output name=%s::%s\n", escapeProperty(name), escapeData(value))
+	return nil
+}
+
+// ExportVariable sets the environment variable for all following steps of the job using the $GITHUB_ENV file
+func ExportVariable(name, value string) error {
+	path := os.Getenv(envFile)
+	if len(path) == 0 {
+		return fmt.Errorf("%s is not set, environment variables can only be exported in github actions", envFile)
+	}
+
+	return appendKeyValue(path, name, value)
+}
+
+// HasSummary checks if the job summary can be written
+func HasSummary() bool {
+	return len(os.Getenv(summaryFile)) > 0
+}
+
+// AppendSummary appends the markdown to the job summary in $GITHUB_STEP_SUMMARY
+func AppendSummary(markdown string) error {
+	path := os.Getenv(summaryFile)
+	if len(path) == 0 {
+		return fmt.Errorf("%s is not set, the job summary can only be written in github actions", summaryFile)
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %v", path, err)
+	}
+	defer file.Close()
+
+	if _, err := file.WriteString(markdown); err != nil {
+		return fmt.Errorf("failed to write the job summary to %s: %v", path, err)
+	}
+
+	return file.Close()
+}
+
+// appendKeyValue appends the value to an environment file like $GITHUB_OUTPUT using the heredoc syntax,
+// the random delimiter allows values to span multiple lines
+func appendKeyValue(path, name, value string) error {
+	delimiter := fmt.Sprintf("ghadelimiter_%s", uuid.New().String())
+	if strings.Contains(name, delimiter) || strings.Contains(value, delimiter) {
+		return fmt.Errorf("value of %s contains the delimiter %s", name, delimiter)
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %v", path, err)
+	}
+	defer file.Close()
+
+	if _, err := fmt.Fprintf(file, "%s<<%s\n%s\n%s\n", name, delimiter, value, delimiter); err != nil {
+		return fmt.Errorf("failed to write %s to %s: %v", name, path, err)
+	}
+
+	return file.Close()
+}
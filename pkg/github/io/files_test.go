@@ -0,0 +1,36 @@
+package io
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+func TestSetOutputFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "output")
+	os.Setenv(outputFile, path)
+	defer os.Unsetenv(outputFile)
+
+	if err := SetOutput("certificate", "-----BEGIN-----\nMIIB\n-----END-----"); err != nil {
+		t.Fatalf("Got unexpected error: %s", err)
+	}
+	if err := SetOutput("name", "app"); err != nil {
+		t.Fatalf("Got unexpected error: %s", err)
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Got unexpected error: %s", err)
+	}
+
+	pattern := regexp.MustCompile(`^certificate<<(ghadelimiter_[0-9a-f-]{36})\n-----BEGIN-----\nMIIB\n-----END-----\n(ghadelimiter_[0-9a-f-]{36})\nname<<(ghadelimiter_[0-9a-f-]{36})\napp\n(ghadelimiter_[0-9a-f-]{36})\n$`)
+	match := pattern.FindStringSubmatch(string(data))
+	if match == nil {
+		t.Fatalf("Got invalid output file:\n%s", data)
+	}
+	if match[1] != match[2] || match[3] != match[4] || match[1] == match[3] {
+		t.Errorf("Got invalid delimiters %v", match[1:])
+	}
+}
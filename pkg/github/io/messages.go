@@ -0,0 +1,64 @@
+/*
+Copyright (c) 2020 white duck Gesellschaft für Softwareentwicklung mbH
+
+This code is licensed under MIT license (see LICENSE for details)
+*/
+package io
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Message is a struct used for printing notice/warning/error annotations
+type Message struct {
+	Message string
+	Title   string
+	File    string
+	Line    string
+	Col     string
+}
+
+// WriteNotice writes the message as notice annotation to the github actions log
+func WriteNotice(message Message) {
+	message.print("notice")
+}
+
+// WriteWarning writes the message as warning annotation to the github actions log
+func WriteWarning(message Message) {
+	message.print("warning")
+}
+
+// WriteError writes the message as error annotation to the github actions log
+func WriteError(message Message) {
+	message.print("error")
+}
+
+func (message Message) print(command string) {
+	var properties []string
+	for _, property := range []struct{ name, value string }{
+		{"title", message.Title},
+		{"file", message.File},
+		{"line", message.Line},
+		{"col", message.Col},
+	} {
+		if len(property.value) > 0 {
+			properties = append(properties, fmt.Sprintf("%s=%s", property.name, escapeProperty(property.value)))
+		}
+	}
+
+	if len(properties) > 0 {
+		command = fmt.Sprintf("%s %s", command, strings.Join(properties, ","))
+	}
+	fmt.Printf("::%s::%s\n", command, escapeData(message.Message))
+}
+
+// escapeData escapes the message of a workflow command, so it can span multiple lines
+func escapeData(v string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(v)
+}
+
+// escapeProperty escapes the property values of a workflow command
+func escapeProperty(v string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(v)
+}
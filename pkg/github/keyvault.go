@@ -0,0 +1,87 @@
+/*
+Copyright (c) 2020 white duck Gesellschaft für Softwareentwicklung mbH
+
+This code is licensed under MIT license (see LICENSE for details)
+*/
+package github
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// keyVaultSecretsKey is the shorthand for key vault references in parameters files
+const keyVaultSecretsKey = "keyVaultSecrets"
+
+// keyVaultPattern matches @keyvault(vaultName, secretName[, version]) in raw parameters
+var keyVaultPattern = regexp.MustCompile(`(?i)^@keyvault\(\s*([^,\s()]+)\s*,\s*([^,\s()]+)\s*(?:,\s*([^,\s()]+)\s*)?\)$`)
+
+// KeyVaultReference is a secret which arm reads from the key vault during the deployment,
+// the vault is a name or a resource id, names are resolved to the id before the deployment
+type KeyVaultReference struct {
+	Vault   string `json:"vault" yaml:"vault"`
+	Secret  string `json:"secret" yaml:"secret"`
+	Version string `json:"version,omitempty" yaml:"version,omitempty"`
+}
+
+// keyVaultParameter returns the parameter which references the secret, the vault name is resolved to its resource id when deploying
+func keyVaultParameter(reference KeyVaultReference) map[string]interface{} {
+	return map[string]interface{}{"reference": reference}
+}
+
+// parseKeyVaultFunction parses @keyvault(vaultName, secretName[, version]), ok is false if the value doesn't use the syntax
+func parseKeyVaultFunction(value string) (KeyVaultReference, bool, error) {
+	if !strings.HasPrefix(strings.ToLower(value), "@keyvault(") {
+		return KeyVaultReference{}, false, nil
+	}
+
+	match := keyVaultPattern.FindStringSubmatch(value)
+	if match == nil {
+		return KeyVaultReference{}, true, fmt.Errorf("invalid key vault reference %s, expected @keyvault(vaultName, secretName[, version])", value)
+	}
+
+	return KeyVaultReference{Vault: match[1], Secret: match[2], Version: match[3]}, true, nil
+}
+
+// extractKeyVaultSecrets removes the keyVaultSecrets shorthand from the parameters file and returns its references,
+// every entry is either vaultName/secretName[/version] or an object with vault, secret and version
+func extractKeyVaultSecrets(contents map[string]interface{}) (map[string]interface{}, error) {
+	secrets, ok := contents[keyVaultSecretsKey]
+	if !ok {
+		return nil, nil
+	}
+	delete(contents, keyVaultSecretsKey)
+
+	entries, ok := secrets.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s must be an object of parameter names and secrets", keyVaultSecretsKey)
+	}
+
+	parameter := make(map[string]interface{}, len(entries))
+	for name, entry := range entries {
+		var reference KeyVaultReference
+		switch entry := entry.(type) {
+		case string:
+			parts := strings.Split(entry, "/")
+			if len(parts) < 2 || len(parts) > 3 {
+				return nil, fmt.Errorf("invalid secret %s for parameter %s, expected vaultName/secretName[/version]", entry, name)
+			}
+			reference = KeyVaultReference{Vault: parts[0], Secret: parts[1]}
+			if len(parts) == 3 {
+				reference.Version = parts[2]
+			}
+		case map[string]interface{}:
+			reference.Vault, _ = entry["vault"].(string)
+			reference.Secret, _ = entry["secret"].(string)
+			reference.Version, _ = entry["version"].(string)
+		}
+
+		if len(reference.Vault) == 0 || len(reference.Secret) == 0 {
+			return nil, fmt.Errorf("invalid secret for parameter %s, the vault and secret are required", name)
+		}
+		parameter[name] = keyVaultParameter(reference)
+	}
+
+	return parameter, nil
+}
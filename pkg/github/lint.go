@@ -0,0 +1,40 @@
+/*
+Copyright (c) 2020 white duck Gesellschaft für Softwareentwicklung mbH
+
+This code is licensed under MIT license (see LICENSE for details)
+*/
+package github
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/whiteducksoftware/azure-arm-action/pkg/lint"
+)
+
+// lintRules overrides the severities of lint rules by their name
+type lintRules map[string]lint.Severity
+
+// wrapParseLintRules parses comma or newline separated RULE=SEVERITY pairs, e.g. unused-parameter=error,hardcoded-location=off
+func wrapParseLintRules(v string) (interface{}, error) {
+	rules := make(lintRules)
+	for _, pair := range strings.FieldsFunc(v, func(r rune) bool { return r == ',' || r == '\n' }) {
+		if pair = strings.TrimSpace(pair); len(pair) == 0 {
+			continue
+		}
+
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid lint rule %s, expected RULE=SEVERITY", pair)
+		}
+
+		severity, err := lint.ParseSeverity(kv[1])
+		if err != nil {
+			return nil, err
+		}
+
+		rules[strings.ToLower(strings.TrimSpace(kv[0]))] = severity
+	}
+
+	return rules, nil
+}
@@ -6,39 +6,96 @@ This code is licensed under MIT license (see LICENSE for details)
 package github
 
 import (
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"time"
-	"unicode"
 
 	"github.com/caarlos0/env/v6"
 	"github.com/sirupsen/logrus"
 	"github.com/whiteducksoftware/azure-arm-action/pkg/util"
 	"github.com/whiteducksoftware/golang-utilities/azure/auth"
 	"github.com/whiteducksoftware/golang-utilities/github/actions"
+	"gopkg.in/yaml.v3"
 )
 
 // Wrapper types to define how we need to parse the input json
 type template map[string]interface{}
 type parameters map[string]interface{}
 
+// link is the uri of a remote template or parameters file, it's empty for local files
+type link string
+
+// Strategies to build the deployment name, everything else is used as go template
+const (
+	NameStrategyUUID   = "uuid"
+	NameStrategyExact  = "exact"
+	NameStrategyRun    = "run"
+	NameStrategyCommit = "commit"
+)
+
+// Formats of the files the outputs are exported to, see exportTo
+const (
+	OutputsFormatJSON   = "json"
+	OutputsFormatYAML   = "yaml"
+	OutputsFormatDotenv = "dotenv"
+)
+
+// ExportToEnv is the exportTo target which exports the outputs as environment variables
+const ExportToEnv = "env"
+
+// Run represents the run context which github provides us, but is missing in actions.GitHub
+type Run struct {
+	RunNumber  uint64 `env:"GITHUB_RUN_NUMBER"`
+	RunAttempt uint64 `env:"GITHUB_RUN_ATTEMPT" envDefault:"1"`
+	Workspace  string `env:"GITHUB_WORKSPACE"`
+}
+
 // Inputs represents our custom inputs for the action
 type Inputs struct {
-	Credentials        *auth.SDKAuth `env:"INPUT_CREDS"`
-	Template           template      `env:"INPUT_TEMPLATELOCATION"`
-	Parameters         parameters    `env:"INPUT_PARAMETERS"`
-	OverrideParameters parameters    `env:"INPUT_OVERRIDEPARAMETERS"`
-	ResourceGroupName  string        `env:"INPUT_RESOURCEGROUPNAME"`
-	ManagementGroupId  string        `env:"INPUT_MANAGEMENTGROUPID"`
-	DeploymentName     string        `env:"INPUT_DEPLOYMENTNAME"`
-	DeploymentMode     string        `env:"INPUT_DEPLOYMENTMODE"`
-	Timeout            time.Duration `env:"INPUT_TIMEOUT" envDefault:"20m"`
+	Credentials               *auth.SDKAuth `env:"INPUT_CREDS"`
+	Template                  template
+	TemplateLink              link `env:"INPUT_TEMPLATELOCATION"`
+	TemplateSourceMap         *util.SourceMap
+	Parameters                parameters       `env:"INPUT_PARAMETERS"`
+	ParametersLink            link             `env:"INPUT_PARAMETERS"`
+	OverrideParameters        parameters       `env:"INPUT_OVERRIDEPARAMETERS"`
+	ParameterSources          parameterSources `env:"INPUT_PARAMETERSOURCES"`
+	ParametersFromEnv         string           `env:"INPUT_PARAMETERSFROMENV"`
+	ValidateParameters        bool             `env:"INPUT_VALIDATEPARAMETERS" envDefault:"true"`
+	SASToken                  string           `env:"INPUT_SASTOKEN"`
+	TemplateSpecID            string           `env:"INPUT_TEMPLATESPECID"`
+	TemplateSpecResourceGroup string           `env:"INPUT_TEMPLATESPECRESOURCEGROUP"`
+	PublishTemplateSpec       bool             `env:"INPUT_PUBLISHTEMPLATESPEC" envDefault:"false"`
+	Scope                     Scope            `env:"INPUT_SCOPE"`
+	ResourceGroupName         string           `env:"INPUT_RESOURCEGROUPNAME"`
+	ManagementGroupId         string           `env:"INPUT_MANAGEMENTGROUPID"`
+	Location                  string           `env:"INPUT_LOCATION"`
+	DeploymentName            string           `env:"INPUT_DEPLOYMENTNAME"`
+	DeploymentNameStrategy    string           `env:"INPUT_DEPLOYMENTNAMESTRATEGY" envDefault:"uuid"`
+	DeploymentMode            string           `env:"INPUT_DEPLOYMENTMODE"`
+	WhatIf                    bool             `env:"INPUT_WHATIF" envDefault:"false"`
+	DeployAfterWhatIf         bool             `env:"INPUT_DEPLOYAFTERWHATIF" envDefault:"false"`
+	SkipUnchanged             bool             `env:"INPUT_SKIPUNCHANGED" envDefault:"false"`
+	OnErrorDeployment         string           `env:"INPUT_ONERRORDEPLOYMENT"`
+	Timeout                   time.Duration    `env:"INPUT_TIMEOUT" envDefault:"20m"`
+	ProgressInterval          time.Duration    `env:"INPUT_PROGRESSINTERVAL" envDefault:"15s"`
+	FlattenOutputs            bool             `env:"INPUT_FLATTENOUTPUTS" envDefault:"false"`
+	ExportTo                  []string         `env:"INPUT_EXPORTTO" envSeparator:","`
+	OutputsFormat             string           `env:"INPUT_OUTPUTSFORMAT"`
+	ExportEnvPrefix           string           `env:"INPUT_EXPORTENVPREFIX" envDefault:"ARM_"`
+	Lint                      bool             `env:"INPUT_LINT" envDefault:"false"`
+	LintRules                 lintRules        `env:"INPUT_LINTRULES"`
 }
 
 // Options is a combined struct of all inputs
 type Options struct {
 	actions.GitHub
+	Run
 	Inputs
 }
 
@@ -49,22 +106,133 @@ func LoadOptions() (Options, error) {
 		return Options{}, err
 	}
 
+	run := Run{}
+	if err := env.Parse(&run); err != nil {
+		return Options{}, fmt.Errorf("failed to parse github environments: %s", err)
+	}
+
 	inputs := Inputs{}
 	if err := env.ParseWithFuncs(&inputs, customTypeParser); err != nil {
 		return Options{}, fmt.Errorf("failed to parse inputs: %s", err)
 	}
 
+	// The template and its source map are read at once, so the file is read and decoded only once
+	if location := os.Getenv("INPUT_TEMPLATELOCATION"); len(location) > 0 {
+		tmpl, sourceMap, err := readTemplate(location)
+		if err != nil {
+			return Options{}, fmt.Errorf("failed to parse inputs: %s", err)
+		}
+		inputs.Template, inputs.TemplateSourceMap = tmpl, sourceMap
+	}
+
+	// Parameters from environment variables are the last source before the override parameters
+	addEnvParameterSources(&inputs)
+
+	// Raw parameters are strings until we know the types the template declares
+	layers := []map[string]interface{}{inputs.Parameters, inputs.OverrideParameters}
+	for _, source := range inputs.ParameterSources {
+		layers = append(layers, source.Parameters)
+	}
+	for _, parameter := range layers {
+		if err := coerceRawParameters(inputs.Template, parameter); err != nil {
+			return Options{}, fmt.Errorf("failed to parse inputs: %s", err)
+		}
+	}
+
+	// Override parameters are merged locally, so they can't be linked
+	if isRemoteFile(os.Getenv("INPUT_OVERRIDEPARAMETERS")) {
+		return Options{}, fmt.Errorf("failed to parse inputs: overrideParameters must be a local file or key value pairs")
+	}
+
+	// Fall back to the scope implied by the resource group or management group
+	if len(inputs.Scope) == 0 {
+		inputs.Scope = inputs.defaultScope()
+	}
+
+	if err := inputs.validate(); err != nil {
+		return Options{}, fmt.Errorf("failed to validate inputs: %s", err)
+	}
+
 	return Options{
 		GitHub: github,
+		Run:    run,
 		Inputs: inputs,
 	}, nil
 }
 
+// validate checks the inputs which depend on each other
+func (inputs Inputs) validate() error {
+	// Linting is offline, it needs neither a scope nor credentials
+	if inputs.Lint {
+		if inputs.Template == nil {
+			return fmt.Errorf("lint requires a local templateLocation")
+		}
+
+		return nil
+	}
+
+	if inputs.Credentials == nil {
+		return fmt.Errorf("creds is required unless lint is used")
+	}
+
+	hasTemplate := inputs.Template != nil || len(inputs.TemplateLink) > 0
+	hasTemplateSpec := len(inputs.TemplateSpecID) > 0
+
+	switch {
+	case inputs.PublishTemplateSpec && (!hasTemplate || !hasTemplateSpec):
+		return fmt.Errorf("publishing a template spec requires templateLocation and templateSpecId")
+	case inputs.PublishTemplateSpec:
+		return nil
+	case hasTemplate && hasTemplateSpec:
+		return fmt.Errorf("templateLocation and templateSpecId can not be combined")
+	case !hasTemplate && !hasTemplateSpec:
+		return fmt.Errorf("either templateLocation or templateSpecId is required")
+	}
+
+	// arm only supports the on error deployment for resource groups
+	if len(inputs.OnErrorDeployment) > 0 && inputs.Scope != ScopeResourceGroup {
+		return fmt.Errorf("onErrorDeployment is only supported for the %s scope", ScopeResourceGroup)
+	}
+
+	switch strings.ToLower(inputs.DeploymentNameStrategy) {
+	case "", NameStrategyUUID, NameStrategyExact, NameStrategyRun, NameStrategyCommit:
+	default:
+		if !strings.Contains(inputs.DeploymentNameStrategy, "{{") {
+			return fmt.Errorf("invalid deploymentNameStrategy %s, expected uuid, exact, run, commit or a go template", inputs.DeploymentNameStrategy)
+		}
+	}
+
+	switch strings.ToLower(inputs.OutputsFormat) {
+	case "", OutputsFormatJSON, OutputsFormatYAML, OutputsFormatDotenv:
+	default:
+		return fmt.Errorf("invalid outputsFormat %s, expected json, yaml or dotenv", inputs.OutputsFormat)
+	}
+
+	return inputs.validateScope()
+}
+
 // custom type parser
 var customTypeParser = map[reflect.Type]env.ParserFunc{
-	reflect.TypeOf(auth.SDKAuth{}): wrapParseServicePrincipal,
-	reflect.TypeOf(template{}):     wrapReadJSON,
-	reflect.TypeOf(parameters{}):   wrapReadParameters,
+	reflect.TypeOf(auth.SDKAuth{}):     wrapParseServicePrincipal,
+	reflect.TypeOf(parameters{}):       wrapReadParameters,
+	reflect.TypeOf(parameterSources{}): wrapParseParameterSources,
+	reflect.TypeOf(link("")):           wrapParseLink,
+	reflect.TypeOf(Scope("")):          wrapParseScope,
+	reflect.TypeOf(lintRules{}):        wrapParseLintRules,
+}
+
+// isRemoteFile checks if the location is an uri which has to be linked instead of read
+func isRemoteFile(v string) bool {
+	return strings.HasPrefix(strings.ToLower(strings.TrimSpace(v)), "https://")
+}
+
+func wrapParseLink(v string) (interface{}, error) {
+	if !isRemoteFile(v) {
+		return link(""), nil
+	}
+
+	logrus.Debugf("Linking remote file %s", v)
+	return link(strings.TrimSpace(v)), nil
 }
 
 func wrapParseServicePrincipal(v string) (interface{}, error) {
@@ -76,76 +244,129 @@ func wrapParseServicePrincipal(v string) (interface{}, error) {
 	return sdkAuth, nil
 }
 
-func wrapReadJSON(v string) (interface{}, error) {
+// readTemplate reads the local template, json templates keep the source map of the file.
+// Remote and compiled bicep templates have no local json we could point to, so they have no source map.
+func readTemplate(v string) (template, *util.SourceMap, error) {
+	// Remote templates are linked, see wrapParseLink
+	if isRemoteFile(v) {
+		return nil, nil, nil
+	}
+
+	// Bicep files have to be compiled to an arm template first
+	if util.IsBicepFile(v) {
+		logrus.Debugf("Compiling bicep file %s", v)
+		contents, err := util.ReadBicep(v)
+		return contents, nil, err
+	}
+
 	logrus.Debugf("Parsing raw json %s", v)
-	return util.ReadJSON(v)
+	contents, sourceMap, err := util.ReadJSONWithSourceMap(filepath.Clean(v))
+	if err != nil {
+		return nil, nil, err
+	}
+	return contents, &sourceMap, nil
 }
 
 func wrapReadParameters(v string) (interface{}, error) {
-	isJSONInput := strings.HasSuffix(v, ".json") // Todo: This check should be more resilient
-	if isJSONInput == true {                     // Check if we are dealing with a path to a json file or raw parameters
-		return wrapReadParametersJSON(v)
+	// Remote parameters are linked, see wrapParseLink
+	if isRemoteFile(v) {
+		return parameters(nil), nil
 	}
 
-	return wrapReadRawParameters(v)
-}
+	// Everything which isn't a file are raw KEY=VALUE parameters
+	if info, err := os.Stat(v); err != nil || info.IsDir() {
+		if isParametersFile(v) {
+			return nil, fmt.Errorf("parameters file %s does not exist", v)
+		}
 
-func wrapReadParametersJSON(v string) (interface{}, error) {
-	logrus.Debugf("Parsing parameter json %s", v)
-	json, err := util.ReadJSON(v)
-	if err != nil {
-		return json, err
+		return wrapReadRawParameters(v)
 	}
 
-	// Check if the parameters are wrapped (https://github.com/Azure/azure-sdk-for-go/issues/9283)
-	paramters, ok := json["parameters"]
-	if ok {
-		return paramters, nil
+	// Bicep parameters files have to be compiled to an arm parameters file first
+	if util.IsBicepParamFile(v) {
+		logrus.Debugf("Compiling bicep parameters file %s", v)
+		contents, err := util.ReadBicepParams(v)
+		if err != nil {
+			return nil, err
+		}
+
+		return unwrapParameters(contents), nil
 	}
 
-	return json, nil
+	return wrapReadParametersFile(v)
 }
 
-func wrapReadRawParameters(v string) (interface{}, error) {
-	parameter := make(map[string]interface{})
-
-	// https://stackoverflow.com/questions/44277222/golang-regular-expression-for-parsing-key-value-pair-into-a-string-map
-	lastQuote := rune(0)
-	f := func(c rune) bool {
-		switch {
-		case c == lastQuote:
-			lastQuote = rune(0)
-			return false
-		case lastQuote != rune(0):
-			return false
-		case unicode.In(c, unicode.Quotation_Mark):
-			lastQuote = c
-			return false
-		default:
-			return unicode.IsSpace(c)
-		}
+// isParametersFile checks if the path has the extension of a supported parameters file
+func isParametersFile(v string) bool {
+	switch strings.ToLower(filepath.Ext(strings.TrimSpace(v))) {
+	case ".json", ".yaml", ".yml", ".bicepparam":
+		return true
+	default:
+		return false
+	}
+}
+
+// wrapReadParametersFile reads a json or yaml parameters file, the format is detected by the content
+func wrapReadParametersFile(v string) (interface{}, error) {
+	data, err := ioutil.ReadFile(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read parameters file %s: %v", v, err)
+	}
+
+	isJSON := json.Valid(data)
+	contents := make(map[string]interface{})
+	if isJSON {
+		logrus.Debugf("Parsing parameter json %s", v)
+		err = json.Unmarshal(data, &contents)
+	} else {
+		logrus.Debugf("Parsing parameter yaml %s", v)
+		err = yaml.Unmarshal(data, &contents)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse parameters file %s, expected json or yaml: %v", v, err)
 	}
 
-	// splitting string by space/newline but considering quoted section
-	pairs := strings.FieldsFunc(v, f)
+	// The keyVaultSecrets shorthand is next to the parameters or the plain values
+	secrets, err := extractKeyVaultSecrets(contents)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse parameters file %s: %v", v, err)
+	}
 
-	for _, keyValuePair := range pairs {
-		keyValue := strings.SplitN(keyValuePair, "=", 2)
-		if len(keyValue) != 2 {
-			return nil, fmt.Errorf("Found invalid pair, expected KEY=VALUE got %s", keyValuePair)
-		}
+	parameter := unwrapParameters(contents)
 
-		// remove all unicode quotation marks (Todo: should really all be removed?)
-		value := strings.Map(func(r rune) rune {
-			if unicode.In(r, unicode.Quotation_Mark) {
-				return -1
+	// yaml files can list the plain values, they are wrapped like in arm parameters files
+	if !isJSON {
+		for name, value := range parameter {
+			if !isParameterValue(value) {
+				parameter[name] = map[string]interface{}{"value": value}
 			}
-			return r
-		}, keyValue[1])
+		}
+	}
 
-		parameter[keyValue[0]] = make(map[string]string)
-		parameter[keyValue[0]].(map[string]string)["value"] = strings.TrimSpace(value)
+	for name, secret := range secrets {
+		parameter[name] = secret
 	}
 
 	return parameter, nil
 }
+
+// unwrapParameters returns the parameters of a complete arm parameters file (https://github.com/Azure/azure-sdk-for-go/issues/9283)
+func unwrapParameters(contents map[string]interface{}) map[string]interface{} {
+	if wrapped, ok := contents["parameters"].(map[string]interface{}); ok {
+		return wrapped
+	}
+
+	return contents
+}
+
+// isParameterValue checks if the value is already in the arm format, an object with a value or a key vault reference
+func isParameterValue(value interface{}) bool {
+	object, ok := value.(map[string]interface{})
+	if !ok || len(object) != 1 {
+		return false
+	}
+
+	_, hasValue := object["value"]
+	_, hasReference := object["reference"]
+	return hasValue || hasReference
+}
@@ -0,0 +1,181 @@
+package github
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/whiteducksoftware/azure-arm-action/pkg/lint"
+	"github.com/whiteducksoftware/golang-utilities/azure/auth"
+)
+
+func TestValidateScope(t *testing.T) {
+	tenantTemplate := template{"$schema": "https://schema.management.azure.com/schemas/2019-08-01/tenantDeploymentTemplate.json#"}
+
+	tests := []struct {
+		name    string
+		inputs  Inputs
+		wantErr string
+	}{
+		{
+			name:   "resource group",
+			inputs: Inputs{Scope: ScopeResourceGroup, ResourceGroupName: "my-group", Template: template{"$schema": "https://schema.management.azure.com/schemas/2019-04-01/deploymentTemplate.json#"}},
+		},
+		{
+			name:    "resource group without name",
+			inputs:  Inputs{Scope: ScopeResourceGroup},
+			wantErr: "requires resourceGroupName",
+		},
+		{
+			name:    "management group without id",
+			inputs:  Inputs{Scope: ScopeManagementGroup, Location: "westeurope"},
+			wantErr: "requires managementGroupId",
+		},
+		{
+			name:    "subscription without location",
+			inputs:  Inputs{Scope: ScopeSubscription},
+			wantErr: "requires location",
+		},
+		{
+			name:    "tenant without location",
+			inputs:  Inputs{Scope: ScopeTenant, Template: tenantTemplate},
+			wantErr: "requires location",
+		},
+		{
+			name:   "tenant",
+			inputs: Inputs{Scope: ScopeTenant, Location: "westeurope", Template: tenantTemplate},
+		},
+		{
+			name:    "schema mismatch",
+			inputs:  Inputs{Scope: ScopeSubscription, Location: "westeurope", Template: tenantTemplate},
+			wantErr: "meant for tenant deployments",
+		},
+		{
+			name:   "unknown schema",
+			inputs: Inputs{Scope: ScopeSubscription, Location: "westeurope", Template: template{"$schema": "https://example.com/schema.json#"}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.inputs.validateScope()
+			switch {
+			case len(test.wantErr) == 0 && err != nil:
+				t.Errorf("Got unexpected error: %s", err)
+			case len(test.wantErr) > 0 && err == nil:
+				t.Errorf("Got no error, expected %q", test.wantErr)
+			case len(test.wantErr) > 0 && !strings.Contains(err.Error(), test.wantErr):
+				t.Errorf("Got invalid error, expected %q got %q", test.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestReadParameters(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"parameters.json":  `{"$schema": "https://schema.management.azure.com/schemas/2019-04-01/deploymentParameters.json#", "parameters": {"name": {"value": "app"}}}`,
+		"parameters":       `{"name": {"value": "app"}}`,
+		"plain.yaml":       "name: app\ncapacity: 2\nranges:\n  - 10.0.0.0/24\n",
+		"wrapped.yml":      "parameters:\n  name:\n    value: app\n",
+		"notyaml.yaml":     "name: [app",
+		"directory.json/x": "",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Got unexpected error: %s", err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Got unexpected error: %s", err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		input   string
+		want    map[string]interface{}
+		wantErr bool
+	}{
+		{name: "json", input: filepath.Join(dir, "parameters.json"), want: map[string]interface{}{"name": map[string]interface{}{"value": "app"}}},
+		{name: "json without extension", input: filepath.Join(dir, "parameters"), want: map[string]interface{}{"name": map[string]interface{}{"value": "app"}}},
+		{name: "plain yaml", input: filepath.Join(dir, "plain.yaml"), want: map[string]interface{}{
+			"name":     map[string]interface{}{"value": "app"},
+			"capacity": map[string]interface{}{"value": 2},
+			"ranges":   map[string]interface{}{"value": []interface{}{"10.0.0.0/24"}},
+		}},
+		{name: "wrapped yaml", input: filepath.Join(dir, "wrapped.yml"), want: map[string]interface{}{"name": map[string]interface{}{"value": "app"}}},
+		{name: "raw", input: "name=app", want: map[string]interface{}{"name": map[string]interface{}{"value": "app"}}},
+		{name: "invalid yaml", input: filepath.Join(dir, "notyaml.yaml"), wantErr: true},
+		{name: "missing file", input: filepath.Join(dir, "missing.json"), wantErr: true},
+		{name: "directory", input: filepath.Join(dir, "directory.json"), wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := wrapReadParameters(test.input)
+			if test.wantErr {
+				if err == nil {
+					t.Errorf("Got no error, parameters %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Got unexpected error: %s", err)
+			}
+
+			// compare the json, as raw parameters are nested string maps
+			gotJSON, _ := json.Marshal(got)
+			wantJSON, _ := json.Marshal(test.want)
+			if string(gotJSON) != string(wantJSON) {
+				t.Errorf("Got invalid parameters, expected %s got %s", wantJSON, gotJSON)
+			}
+		})
+	}
+}
+
+func TestParseLintRules(t *testing.T) {
+	parsed, err := wrapParseLintRules("unused-parameter=error, Hardcoded-Location = off\nsecret-output=warning")
+	if err != nil {
+		t.Fatalf("Got unexpected error: %s", err)
+	}
+
+	want := lintRules{"unused-parameter": lint.SeverityError, "hardcoded-location": lint.SeverityOff, "secret-output": lint.SeverityWarning}
+	if !reflect.DeepEqual(parsed, want) {
+		t.Errorf("Got invalid lint rules, expected %v got %v", want, parsed)
+	}
+
+	for _, invalid := range []string{"unused-parameter", "unused-parameter=fatal"} {
+		if _, err := wrapParseLintRules(invalid); err == nil {
+			t.Errorf("Got no error for %s", invalid)
+		}
+	}
+}
+
+func TestValidateCredentials(t *testing.T) {
+	tmpl := template{"$schema": "https://schema.management.azure.com/schemas/2019-04-01/deploymentTemplate.json#"}
+
+	deploy := Inputs{Scope: ScopeResourceGroup, ResourceGroupName: "my-group", Template: tmpl}
+	if err := deploy.validate(); err == nil || !strings.Contains(err.Error(), "creds is required") {
+		t.Errorf("Got invalid error for a deployment without creds: %v", err)
+	}
+
+	publish := Inputs{PublishTemplateSpec: true, TemplateSpecID: "app:1.0", Template: tmpl}
+	if err := publish.validate(); err == nil || !strings.Contains(err.Error(), "creds is required") {
+		t.Errorf("Got invalid error for publishing without creds: %v", err)
+	}
+
+	deploy.Credentials = &auth.SDKAuth{}
+	if err := deploy.validate(); err != nil {
+		t.Errorf("Got unexpected error: %s", err)
+	}
+
+	linting := Inputs{Lint: true, Template: tmpl}
+	if err := linting.validate(); err != nil {
+		t.Errorf("Got unexpected error for lint without creds: %s", err)
+	}
+}
@@ -0,0 +1,448 @@
+/*
+Copyright (c) 2020 white duck Gesellschaft für Softwareentwicklung mbH
+
+This code is licensed under MIT license (see LICENSE for details)
+*/
+package github
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/sirupsen/logrus"
+	"github.com/whiteducksoftware/azure-arm-action/pkg/util"
+)
+
+// Names of the parameter layers of the parameters and overrideParameters inputs
+const (
+	ParametersLayer         = "parameters"
+	OverrideParametersLayer = "overrideParameters"
+)
+
+// envSourcePrefix marks a parameter source which collects the environment variables with the prefix
+const envSourcePrefix = "env:"
+
+// inlineKeyPattern matches the key of an inline KEY=VALUE parameter source
+var inlineKeyPattern = regexp.MustCompile(`^[A-Za-z_][\w-]*$`)
+
+// ParameterSource is a layer of parameters read from a file, inline pairs or environment variables
+type ParameterSource struct {
+	Name       string
+	Parameters map[string]interface{}
+	DeepMerge  bool
+
+	// fromEnv marks the environment variable sources, their undeclared parameters are dropped
+	fromEnv bool
+}
+
+// parameterSources are the layers of the parameterSources input in the order of their lines
+type parameterSources []ParameterSource
+
+// rawValue is the value of a raw KEY=VALUE parameter, it's converted
+// to the type of the template parameter by coerceRawParameters
+type rawValue string
+
+// wrapReadRawParameters parses space delimited KEY=VALUE pairs. Values can be quoted with ' or ",
+// double quoted values and unquoted values support backslash escapes, e.g. name="my \"app\"" or name=my\ app.
+// Only whitespace, quotes, = and \ can be escaped, other backslashes are kept, e.g. path=C:\temp.
+// Quotes are only special at the start of a value, so json literals like ranges=["a","b"] don't need quoting.
+func wrapReadRawParameters(v string) (interface{}, error) {
+	pairs, err := splitRawParameters(v)
+	if err != nil {
+		return nil, err
+	}
+
+	parameter := make(map[string]interface{})
+	for _, pair := range pairs {
+		if len(pair.key) == 0 || !pair.hasValue {
+			return nil, fmt.Errorf("Found invalid pair, expected KEY=VALUE got %s", pair.raw)
+		}
+
+		reference, isReference, err := parseKeyVaultFunction(pair.value)
+		if err != nil {
+			return nil, err
+		}
+		if isReference {
+			parameter[pair.key] = keyVaultParameter(reference)
+			continue
+		}
+
+		parameter[pair.key] = map[string]interface{}{"value": rawValue(pair.value)}
+	}
+
+	return parameter, nil
+}
+
+// escapableCharacters are the characters a backslash escapes, before every other character it's a plain backslash
+const escapableCharacters = " \t\n\"'=\\"
+
+type rawPair struct {
+	key      string
+	value    string
+	hasValue bool
+	raw      string
+}
+
+// splitRawParameters splits the input by unquoted whitespace, the key ends at the first unescaped and unquoted =
+func splitRawParameters(v string) ([]rawPair, error) {
+	var pairs []rawPair
+	var current *rawPair
+	var sb strings.Builder
+	quote := rune(0)
+	escaped := false
+	partStart := false
+	start := 0
+
+	finish := func(end int) {
+		if current == nil {
+			return
+		}
+		if current.hasValue {
+			current.value = sb.String()
+		} else {
+			current.key = sb.String()
+		}
+		current.raw = v[start:end]
+		pairs = append(pairs, *current)
+		current = nil
+		sb.Reset()
+	}
+
+	for i, c := range v {
+		if current == nil {
+			if unicode.IsSpace(c) {
+				continue
+			}
+			current = &rawPair{}
+			start = i
+			partStart = true
+		}
+
+		atStart := partStart
+		partStart = false
+
+		switch {
+		case escaped:
+			sb.WriteRune(c)
+			escaped = false
+		case c == '\\' && quote != '\'' && i+1 < len(v) && strings.ContainsRune(escapableCharacters, rune(v[i+1])):
+			escaped = true
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote != 0:
+			sb.WriteRune(c)
+		case atStart && (c == '"' || c == '\''):
+			quote = c
+		case c == '=' && !current.hasValue:
+			current.key = strings.TrimSpace(sb.String())
+			current.hasValue = true
+			partStart = true
+			sb.Reset()
+		case unicode.IsSpace(c):
+			finish(i)
+		default:
+			sb.WriteRune(c)
+		}
+	}
+
+	if quote != 0 {
+		return nil, fmt.Errorf("Found unterminated quote %c in raw parameters", quote)
+	}
+	if escaped {
+		return nil, fmt.Errorf("Found trailing backslash in raw parameters")
+	}
+	finish(len(v))
+
+	return pairs, nil
+}
+
+// coerceRawParameters converts the values of raw parameters to the type the template declares,
+// parameters unknown to the template (e.g. of linked templates) stay strings
+func coerceRawParameters(tmpl template, parameter map[string]interface{}) error {
+	declarations, _ := tmpl["parameters"].(map[string]interface{})
+	for name, value := range parameter {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		raw, ok := object["value"].(rawValue)
+		if !ok {
+			continue
+		}
+
+		declaredName, parameterType := parameterDeclaration(declarations, name)
+		coerced, err := coerceRawValue(parameterType, string(raw))
+		if err != nil {
+			return fmt.Errorf("invalid value for parameter %s: %s", name, err)
+		}
+		object["value"] = coerced
+
+		// Environment variables are usually upper case, use the name the template declares
+		if len(declaredName) > 0 && declaredName != name {
+			delete(parameter, name)
+			parameter[declaredName] = object
+		}
+	}
+
+	return nil
+}
+
+// parameterDeclaration returns the declared name and type of the parameter, arm treats the names case-insensitive
+// but an exact match is preferred.
+// Names of environment variables like STORAGE_NAME match the parameter storageName, if no parameter matches exactly.
+func parameterDeclaration(declarations map[string]interface{}, name string) (string, string) {
+	key, ok := name, declarations[name] != nil
+	if !ok {
+		key, ok = findDeclaration(declarations, func(key string) bool {
+			return strings.EqualFold(key, name)
+		})
+	}
+	if !ok {
+		key, ok = findDeclaration(declarations, func(key string) bool {
+			return strings.EqualFold(key, strings.ReplaceAll(name, "_", ""))
+		})
+	}
+	if !ok {
+		return "", ""
+	}
+
+	declaration, _ := declarations[key].(map[string]interface{})
+	parameterType, _ := declaration["type"].(string)
+	return key, strings.ToLower(parameterType)
+}
+
+// findDeclaration returns the first matching declared name in sorted order, so names which only differ in case
+// or underscores always resolve to the same declaration
+func findDeclaration(declarations map[string]interface{}, match func(key string) bool) (string, bool) {
+	keys := make([]string, 0, len(declarations))
+	for key := range declarations {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if match(key) {
+			return key, true
+		}
+	}
+
+	return "", false
+}
+
+// addEnvParameterSources appends the parametersFromEnv source and drops the parameters of all environment
+// variable sources which the template doesn't declare, other variables can share the prefix
+func addEnvParameterSources(inputs *Inputs) {
+	if len(inputs.ParametersFromEnv) > 0 {
+		parameter := readEnvParameters(inputs.ParametersFromEnv)
+		inputs.ParameterSources = append(inputs.ParameterSources, ParameterSource{Name: envSourcePrefix + inputs.ParametersFromEnv, Parameters: parameter, fromEnv: true})
+	}
+
+	for _, source := range inputs.ParameterSources {
+		if source.fromEnv {
+			dropUndeclaredParameters(inputs.Template, source.Parameters)
+		}
+	}
+}
+
+// dropUndeclaredParameters removes the parameters the template doesn't declare, arm rejects them. Nothing is
+// removed if the template is unknown locally, e.g. a linked template or a template spec.
+func dropUndeclaredParameters(tmpl template, parameter map[string]interface{}) {
+	declarations, ok := tmpl["parameters"].(map[string]interface{})
+	if !ok {
+		return
+	}
+
+	for name := range parameter {
+		if declaredName, _ := parameterDeclaration(declarations, name); len(declaredName) == 0 {
+			logrus.Warnf("Ignoring environment variable for parameter %s, the template doesn't declare it", name)
+			delete(parameter, name)
+		}
+	}
+}
+
+func coerceRawValue(parameterType, value string) (interface{}, error) {
+	switch parameterType {
+	case "int":
+		i, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("expected int, got %s", value)
+		}
+		return i, nil
+	case "bool":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("expected bool, got %s", value)
+		}
+		return b, nil
+	case "array":
+		var array []interface{}
+		if err := json.Unmarshal([]byte(value), &array); err != nil {
+			return nil, fmt.Errorf("expected json array, got %s", value)
+		}
+		return array, nil
+	case "object", "secureobject":
+		var object map[string]interface{}
+		if err := json.Unmarshal([]byte(value), &object); err != nil || object == nil {
+			return nil, fmt.Errorf("expected json object, got %s", value)
+		}
+		return object, nil
+	default:
+		return value, nil
+	}
+}
+
+// wrapParseParameterSources parses one source per line, prefixed by optional options in brackets:
+//
+//	[merge=deep,optional] params/*.yaml
+//
+// Sources are files, globs (every match is a layer), env:PREFIX for environment variables or inline KEY=VALUE pairs.
+func wrapParseParameterSources(v string) (interface{}, error) {
+	var sources parameterSources
+	for _, line := range strings.Split(v, "\n") {
+		line = strings.TrimSpace(line)
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		layerSources, err := parseParameterSource(line)
+		if err != nil {
+			return nil, fmt.Errorf("invalid parameter source %s: %s", line, err)
+		}
+		sources = append(sources, layerSources...)
+	}
+
+	return sources, nil
+}
+
+func parseParameterSource(line string) ([]ParameterSource, error) {
+	deepMerge, optional := false, false
+	if strings.HasPrefix(line, "[") {
+		end := strings.Index(line, "]")
+		if end < 0 {
+			return nil, fmt.Errorf("missing ] after the options")
+		}
+
+		for _, option := range strings.Split(line[1:end], ",") {
+			switch strings.ToLower(strings.ReplaceAll(option, " ", "")) {
+			case "":
+			case "merge=deep":
+				deepMerge = true
+			case "merge=replace":
+				deepMerge = false
+			case "optional":
+				optional = true
+			default:
+				return nil, fmt.Errorf("unknown option %s, expected merge=deep, merge=replace or optional", strings.TrimSpace(option))
+			}
+		}
+		line = strings.TrimSpace(line[end+1:])
+	}
+
+	var paths []string
+	switch {
+	case isRemoteFile(line):
+		return nil, fmt.Errorf("remote parameters files can't be merged locally")
+	case strings.HasPrefix(strings.ToLower(line), envSourcePrefix):
+		prefix := strings.TrimSpace(line[len(envSourcePrefix):])
+		return []ParameterSource{{Name: line, Parameters: readEnvParameters(prefix), DeepMerge: deepMerge, fromEnv: true}}, nil
+	case isInlineParameters(line):
+		parameter, err := wrapReadRawParameters(line)
+		if err != nil {
+			return nil, err
+		}
+		return []ParameterSource{{Name: "inline parameters", Parameters: parameter.(map[string]interface{}), DeepMerge: deepMerge}}, nil
+	case strings.ContainsAny(line, "*?["):
+		matches, err := filepath.Glob(line)
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(matches)
+		paths = matches
+	case isParametersFile(line) || isExistingFile(line):
+		if isExistingFile(line) {
+			paths = []string{line}
+		}
+	default:
+		parameter, err := wrapReadRawParameters(line)
+		if err != nil {
+			return nil, err
+		}
+		return []ParameterSource{{Name: "inline parameters", Parameters: parameter.(map[string]interface{}), DeepMerge: deepMerge}}, nil
+	}
+
+	if len(paths) == 0 {
+		if optional {
+			logrus.Debugf("Skipping optional parameter source %s, no file found", line)
+			return nil, nil
+		}
+		return nil, fmt.Errorf("no parameters file found")
+	}
+
+	var sources []ParameterSource
+	for _, path := range paths {
+		parameter, err := wrapReadParameters(path)
+		if err != nil {
+			return nil, err
+		}
+
+		parameterMap, _ := parameter.(map[string]interface{})
+		sources = append(sources, ParameterSource{Name: path, Parameters: parameterMap, DeepMerge: deepMerge})
+	}
+
+	return sources, nil
+}
+
+// readEnvParameters collects the environment variables with the prefix as raw parameters, the prefix is removed from the name
+func readEnvParameters(prefix string) map[string]interface{} {
+	parameter := make(map[string]interface{})
+	for _, variable := range os.Environ() {
+		keyValue := strings.SplitN(variable, "=", 2)
+		if len(keyValue) != 2 || len(keyValue[0]) <= len(prefix) || !strings.HasPrefix(keyValue[0], prefix) {
+			continue
+		}
+
+		parameter[keyValue[0][len(prefix):]] = map[string]interface{}{"value": rawValue(keyValue[1])}
+	}
+
+	return parameter
+}
+
+// isInlineParameters checks if the source starts with KEY= or a quoted key, so values like ranges=["10.0.0.0/8"]
+// or name=app.json aren't mistaken for a glob or file
+func isInlineParameters(line string) bool {
+	if strings.HasPrefix(line, `"`) || strings.HasPrefix(line, "'") {
+		return true
+	}
+
+	end := strings.Index(line, "=")
+	return end > 0 && inlineKeyPattern.MatchString(line[:end])
+}
+
+func isExistingFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
+
+// ParameterLayers returns all parameter sources in the order they are merged,
+// parameters first, then the parameterSources and overrideParameters last
+func (inputs Inputs) ParameterLayers() []util.ParameterLayer {
+	layers := []util.ParameterLayer{{Name: ParametersLayer, Parameters: inputs.Parameters}}
+	for _, source := range inputs.ParameterSources {
+		layers = append(layers, util.ParameterLayer{Name: source.Name, Parameters: source.Parameters, DeepMerge: source.DeepMerge})
+	}
+
+	return append(layers, util.ParameterLayer{Name: OverrideParametersLayer, Parameters: inputs.OverrideParameters})
+}
+
+// HasLocalParameters checks if any parameters besides the parameters input have to be merged locally
+func (inputs Inputs) HasLocalParameters() bool {
+	return len(inputs.OverrideParameters) > 0 || len(inputs.ParameterSources) > 0
+}
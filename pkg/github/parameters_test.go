@@ -0,0 +1,278 @@
+package github
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRawParameters(t *testing.T) {
+	tmpl := template{
+		"parameters": map[string]interface{}{
+			"capacity": map[string]interface{}{"type": "int"},
+			"enabled":  map[string]interface{}{"type": "Bool"},
+			"ranges":   map[string]interface{}{"type": "array"},
+			"tags":     map[string]interface{}{"type": "object"},
+			"name":     map[string]interface{}{"type": "string"},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{name: "typed", input: `capacity=2 enabled=true ranges=["10.0.0.0/24","10.0.1.0/24"] tags='{"env": "prod"}'`,
+			want: `{"capacity":{"value":2},"enabled":{"value":true},"ranges":{"value":["10.0.0.0/24","10.0.1.0/24"]},"tags":{"value":{"env":"prod"}}}`},
+		{name: "numeric string", input: `name=42`, want: `{"name":{"value":"42"}}`},
+		{name: "inner quotes", input: `name=it's other=say"hi"`, want: `{"name":{"value":"it's"},"other":{"value":"say\"hi\""}}`},
+		{name: "unknown parameter", input: `other=[1]`, want: `{"other":{"value":"[1]"}}`},
+		{name: "quoted", input: `name="my \"app\" = 1" other='it\s'`, want: `{"name":{"value":"my \"app\" = 1"},"other":{"value":"it\\s"}}`},
+		{name: "escaped", input: "name=my\\ app\\\"s\nother=a=b", want: `{"name":{"value":"my app\"s"},"other":{"value":"a=b"}}`},
+		{name: "backslashes", input: `path=C:\temp\dir quoted="C:\temp\\dir" share=\\server\share`, want: `{"path":{"value":"C:\\temp\\dir"},"quoted":{"value":"C:\\temp\\dir"},"share":{"value":"\\server\\share"}}`},
+		{name: "empty value", input: `name=""`, want: `{"name":{"value":""}}`},
+		{name: "invalid int", input: `capacity=two`, wantErr: true},
+		{name: "invalid array", input: `ranges=10.0.0.0/24`, wantErr: true},
+		{name: "missing value", input: `name`, wantErr: true},
+		{name: "unterminated quote", input: `name="app`, wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			parsed, err := wrapReadRawParameters(test.input)
+			if err == nil {
+				err = coerceRawParameters(tmpl, parsed.(map[string]interface{}))
+			}
+			if test.wantErr {
+				if err == nil {
+					t.Errorf("Got no error for %s", test.input)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Got unexpected error: %s", err)
+			}
+
+			got, _ := json.Marshal(parsed)
+			if string(got) != test.want {
+				t.Errorf("Got invalid parameters, expected %s got %s", test.want, got)
+			}
+		})
+	}
+}
+
+func TestParameterSources(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"base.json":      `{"parameters": {"location": {"value": "westeurope"}}}`,
+		"regions/a.yaml": "region: a\n",
+		"regions/b.yaml": "region: b\n",
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Got unexpected error: %s", err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Got unexpected error: %s", err)
+		}
+	}
+
+	os.Setenv("TEST_PARAM_CAPACITY", "3")
+	defer os.Unsetenv("TEST_PARAM_CAPACITY")
+
+	input := strings.Join([]string{
+		"# comment",
+		filepath.Join(dir, "base.json"),
+		"[merge=deep] " + filepath.Join(dir, "regions", "*.yaml"),
+		"[optional] " + filepath.Join(dir, "missing.json"),
+		"env:TEST_PARAM_",
+		"name=app",
+		`ranges=["10.0.0.0/8"] file=app.json`,
+	}, "\n")
+
+	parsed, err := wrapParseParameterSources(input)
+	if err != nil {
+		t.Fatalf("Got unexpected error: %s", err)
+	}
+	sources := parsed.(parameterSources)
+
+	wantNames := []string{filepath.Join(dir, "base.json"), filepath.Join(dir, "regions", "a.yaml"), filepath.Join(dir, "regions", "b.yaml"), "env:TEST_PARAM_", "inline parameters", "inline parameters"}
+	if len(sources) != len(wantNames) {
+		t.Fatalf("Got invalid count of sources, expected %d got %d", len(wantNames), len(sources))
+	}
+	for i, name := range wantNames {
+		if sources[i].Name != name {
+			t.Errorf("Got invalid source %d, expected %s got %s", i, name, sources[i].Name)
+		}
+	}
+	if sources[0].DeepMerge || !sources[1].DeepMerge || !sources[2].DeepMerge {
+		t.Errorf("Got invalid merge options: %v", sources)
+	}
+
+	tmpl := template{"parameters": map[string]interface{}{
+		"capacity": map[string]interface{}{"type": "int"},
+		"ranges":   map[string]interface{}{"type": "array"},
+		"file":     map[string]interface{}{"type": "string"},
+	}}
+	if err := coerceRawParameters(tmpl, sources[5].Parameters); err != nil {
+		t.Fatalf("Got unexpected error: %s", err)
+	}
+	if got, _ := json.Marshal(sources[5].Parameters); string(got) != `{"file":{"value":"app.json"},"ranges":{"value":["10.0.0.0/8"]}}` {
+		t.Errorf("Got invalid inline parameters %s", got)
+	}
+
+	if err := coerceRawParameters(tmpl, sources[3].Parameters); err != nil {
+		t.Fatalf("Got unexpected error: %s", err)
+	}
+	if got, _ := json.Marshal(sources[3].Parameters); string(got) != `{"capacity":{"value":3}}` {
+		t.Errorf("Got invalid environment parameters %s", got)
+	}
+
+	for _, invalid := range []string{filepath.Join(dir, "missing.json"), "[merge=shallow] name=app", "[optional name=app"} {
+		if _, err := wrapParseParameterSources(invalid); err == nil {
+			t.Errorf("Got no error for source %s", invalid)
+		}
+	}
+}
+
+func TestParametersFromEnv(t *testing.T) {
+	for name, value := range map[string]string{
+		"TEST_ENV_STORAGE_NAME": "mystorage",
+		"TEST_ENV_SKUCAPACITY":  "2",
+		"TEST_ENV_UNKNOWN":      "x",
+	} {
+		os.Setenv(name, value)
+		defer os.Unsetenv(name)
+	}
+
+	tmpl := template{"parameters": map[string]interface{}{
+		"storageName": map[string]interface{}{"type": "string"},
+		"skuCapacity": map[string]interface{}{"type": "int"},
+	}}
+
+	parameter := readEnvParameters("TEST_ENV_")
+	dropUndeclaredParameters(tmpl, parameter)
+	if err := coerceRawParameters(tmpl, parameter); err != nil {
+		t.Fatalf("Got unexpected error: %s", err)
+	}
+
+	got, _ := json.Marshal(parameter)
+	want := `{"skuCapacity":{"value":2},"storageName":{"value":"mystorage"}}`
+	if string(got) != want {
+		t.Errorf("Got invalid parameters, expected %s got %s", want, got)
+	}
+}
+
+func TestKeyVaultReferences(t *testing.T) {
+	parsed, err := wrapReadRawParameters(`password=@keyvault(myvault,admin-password) cert="@KeyVault(myvault, cert, v1)"`)
+	if err != nil {
+		t.Fatalf("Got unexpected error: %s", err)
+	}
+
+	got, _ := json.Marshal(parsed)
+	want := `{"cert":{"reference":{"vault":"myvault","secret":"cert","version":"v1"}},"password":{"reference":{"vault":"myvault","secret":"admin-password"}}}`
+	if string(got) != want {
+		t.Errorf("Got invalid raw parameters, expected %s got %s", want, got)
+	}
+
+	if _, err := wrapReadRawParameters(`password=@keyvault(myvault)`); err == nil {
+		t.Errorf("Got no error for an invalid key vault reference")
+	}
+
+	path := filepath.Join(t.TempDir(), "parameters.yaml")
+	content := "name: app\nkeyVaultSecrets:\n  password: myvault/admin-password\n  cert:\n    vault: myvault\n    secret: cert\n    version: v1\n"
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Got unexpected error: %s", err)
+	}
+
+	parsed, err = wrapReadParameters(path)
+	if err != nil {
+		t.Fatalf("Got unexpected error: %s", err)
+	}
+
+	got, _ = json.Marshal(parsed)
+	want = `{"cert":{"reference":{"vault":"myvault","secret":"cert","version":"v1"}},"name":{"value":"app"},"password":{"reference":{"vault":"myvault","secret":"admin-password"}}}`
+	if string(got) != want {
+		t.Errorf("Got invalid file parameters, expected %s got %s", want, got)
+	}
+}
+
+func TestEnvParameterSources(t *testing.T) {
+	for name, value := range map[string]string{
+		"TEST_SOURCE_CAPACITY": "2",
+		"TEST_SOURCE_UNKNOWN":  "x",
+		"TEST_FROM_ENV_NAME":   "app",
+		"TEST_FROM_ENV_OTHER":  "y",
+	} {
+		os.Setenv(name, value)
+		defer os.Unsetenv(name)
+	}
+
+	parsed, err := wrapParseParameterSources("env:TEST_SOURCE_\nunknown=inline")
+	if err != nil {
+		t.Fatalf("Got unexpected error: %s", err)
+	}
+
+	inputs := Inputs{
+		Template: template{"parameters": map[string]interface{}{
+			"capacity": map[string]interface{}{"type": "int"},
+			"name":     map[string]interface{}{"type": "string"},
+		}},
+		ParameterSources:  parsed.(parameterSources),
+		ParametersFromEnv: "TEST_FROM_ENV_",
+	}
+	addEnvParameterSources(&inputs)
+
+	want := map[string]string{
+		"env:TEST_SOURCE_":   `{"CAPACITY":{"value":"2"}}`,
+		"inline parameters":  `{"unknown":{"value":"inline"}}`,
+		"env:TEST_FROM_ENV_": `{"NAME":{"value":"app"}}`,
+	}
+	if len(inputs.ParameterSources) != len(want) {
+		t.Fatalf("Got invalid count of sources, expected %d got %d", len(want), len(inputs.ParameterSources))
+	}
+	for _, source := range inputs.ParameterSources {
+		// Only environment variables are dropped, other undeclared parameters are reported by the parameter check
+		if got, _ := json.Marshal(source.Parameters); string(got) != want[source.Name] {
+			t.Errorf("Got invalid parameters for %s, expected %s got %s", source.Name, want[source.Name], got)
+		}
+	}
+}
+
+func TestParameterDeclaration(t *testing.T) {
+	declarations := map[string]interface{}{
+		"storageName":  map[string]interface{}{"type": "string"},
+		"StorageName":  map[string]interface{}{"type": "String"},
+		"storage_name": map[string]interface{}{"type": "int"},
+		"skuCapacity":  map[string]interface{}{"type": "int"},
+	}
+
+	tests := []struct {
+		name     string
+		wantName string
+		wantType string
+	}{
+		{name: "storageName", wantName: "storageName", wantType: "string"},
+		{name: "StorageName", wantName: "StorageName", wantType: "string"},
+		{name: "STORAGENAME", wantName: "StorageName", wantType: "string"},
+		{name: "STORAGE_NAME", wantName: "storage_name", wantType: "int"},
+		{name: "SKU_CAPACITY", wantName: "skuCapacity", wantType: "int"},
+		{name: "unknown", wantName: "", wantType: ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Maps are iterated in random order, ambiguous names must resolve the same way every time
+			for i := 0; i < 20; i++ {
+				declaredName, parameterType := parameterDeclaration(declarations, test.name)
+				if declaredName != test.wantName || parameterType != test.wantType {
+					t.Fatalf("Got invalid declaration, expected %s (%s) got %s (%s)", test.wantName, test.wantType, declaredName, parameterType)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+/*
+Copyright (c) 2020 white duck Gesellschaft für Softwareentwicklung mbH
+
+This code is licensed under MIT license (see LICENSE for details)
+*/
+package github
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Scope is the scope at which the template is deployed
+type Scope string
+
+// All scopes which are supported by arm deployments
+const (
+	ScopeResourceGroup   Scope = "resourceGroup"
+	ScopeSubscription    Scope = "subscription"
+	ScopeManagementGroup Scope = "managementGroup"
+	ScopeTenant          Scope = "tenant"
+)
+
+// scopeSchemas maps the scopes to the file name of the template $schema they require
+var scopeSchemas = map[Scope]string{
+	ScopeResourceGroup:   "deploymentTemplate.json",
+	ScopeSubscription:    "subscriptionDeploymentTemplate.json",
+	ScopeManagementGroup: "managementGroupDeploymentTemplate.json",
+	ScopeTenant:          "tenantDeploymentTemplate.json",
+}
+
+func wrapParseScope(v string) (interface{}, error) {
+	for scope := range scopeSchemas {
+		if strings.EqualFold(strings.TrimSpace(v), string(scope)) {
+			return scope, nil
+		}
+	}
+
+	return nil, fmt.Errorf("invalid scope %s, expected one of resourceGroup, subscription, managementGroup or tenant", v)
+}
+
+// defaultScope returns the scope implied by the resource group or management group input
+func (inputs Inputs) defaultScope() Scope {
+	switch {
+	case len(inputs.ResourceGroupName) > 0:
+		return ScopeResourceGroup
+	case len(inputs.ManagementGroupId) > 0:
+		return ScopeManagementGroup
+	default:
+		return ScopeSubscription
+	}
+}
+
+// validateScope checks that the scope has its required inputs (arm requires a location
+// for the deployment data above the resource group) and that
+// the $schema of the template matches the scope
+func (inputs Inputs) validateScope() error {
+	switch {
+	case inputs.Scope == ScopeResourceGroup && len(inputs.ResourceGroupName) == 0:
+		return fmt.Errorf("scope %s requires resourceGroupName", inputs.Scope)
+	case inputs.Scope == ScopeManagementGroup && len(inputs.ManagementGroupId) == 0:
+		return fmt.Errorf("scope %s requires managementGroupId", inputs.Scope)
+	case inputs.Scope != ScopeResourceGroup && len(inputs.Location) == 0:
+		return fmt.Errorf("scope %s requires location to store the deployment data", inputs.Scope)
+	}
+
+	// Linked templates and template specs are checked by arm
+	schema, ok := inputs.Template["$schema"].(string)
+	if !ok {
+		return nil
+	}
+
+	schemaFile := strings.TrimSuffix(schema, "#")
+	if i := strings.LastIndex(schemaFile, "/"); i >= 0 {
+		schemaFile = schemaFile[i+1:]
+	}
+
+	// Unknown schemas are left to arm, we only report clear mismatches
+	for scope, file := range scopeSchemas {
+		if scope != inputs.Scope && strings.EqualFold(schemaFile, file) {
+			return fmt.Errorf("the template $schema %s is meant for %s deployments, but scope is %s (expected %s)", schema, scope, inputs.Scope, scopeSchemas[inputs.Scope])
+		}
+	}
+
+	return nil
+}
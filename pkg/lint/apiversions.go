@@ -0,0 +1,23 @@
+/*
+Copyright (c) 2020 white duck Gesellschaft für Softwareentwicklung mbH
+
+This code is licensed under MIT license (see LICENSE for details)
+*/
+package lint
+
+// minimumAPIVersions is the bundled table of the oldest api version which isn't deprecated for common resource types,
+// the keys are lower case. Types which aren't listed aren't checked.
+var minimumAPIVersions = map[string]string{
+	"microsoft.compute/virtualmachines":          "2019-07-01",
+	"microsoft.containerservice/managedclusters": "2021-03-01",
+	"microsoft.keyvault/vaults":                  "2019-09-01",
+	"microsoft.network/networksecuritygroups":    "2020-05-01",
+	"microsoft.network/publicipaddresses":        "2020-05-01",
+	"microsoft.network/virtualnetworks":          "2020-05-01",
+	"microsoft.network/virtualnetworks/subnets":  "2020-05-01",
+	"microsoft.sql/servers":                      "2019-06-01-preview",
+	"microsoft.sql/servers/databases":            "2019-06-01-preview",
+	"microsoft.storage/storageaccounts":          "2019-06-01",
+	"microsoft.web/serverfarms":                  "2018-02-01",
+	"microsoft.web/sites":                        "2018-11-01",
+}
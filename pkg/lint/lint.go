@@ -0,0 +1,109 @@
+/*
+Copyright (c) 2020 white duck Gesellschaft für Softwareentwicklung mbH
+
+This code is licensed under MIT license (see LICENSE for details)
+*/
+
+// Package lint checks arm templates offline with a set of rules
+package lint
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Severity is the level a finding is reported with
+type Severity string
+
+// Severities of findings, findings of rules which are off aren't reported
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+	SeverityNotice  Severity = "notice"
+	SeverityOff     Severity = "off"
+)
+
+// ParseSeverity parses the severity case-insensitive
+func ParseSeverity(v string) (Severity, error) {
+	switch severity := Severity(strings.ToLower(strings.TrimSpace(v))); severity {
+	case SeverityError, SeverityWarning, SeverityNotice, SeverityOff:
+		return severity, nil
+	default:
+		return "", fmt.Errorf("invalid severity %s, expected error, warning, notice or off", v)
+	}
+}
+
+// Finding is a problem a rule found in the template, the path is the json path of the problem, e.g. resources[0].apiVersion
+type Finding struct {
+	Rule     string
+	Severity Severity
+	Message  string
+	Path     string
+}
+
+// Rule checks the template for one kind of problem
+type Rule struct {
+	Name        string
+	Description string
+	Severity    Severity
+	Check       func(template Template) []Finding
+}
+
+var registry []Rule
+
+// Register adds the rule to the rules which are run by Run
+func Register(rule Rule) {
+	registry = append(registry, rule)
+}
+
+// Rules returns all registered rules
+func Rules() []Rule {
+	return append([]Rule(nil), registry...)
+}
+
+// Run checks the template with all registered rules, the severities override the default severity of the rules by name.
+// The findings are sorted by path and rule.
+func Run(template map[string]interface{}, severities map[string]Severity) ([]Finding, error) {
+	for name := range severities {
+		if !isRegistered(name) {
+			return nil, fmt.Errorf("unknown lint rule %s", name)
+		}
+	}
+
+	var findings []Finding
+	for _, rule := range registry {
+		severity := rule.Severity
+		if override, ok := severities[rule.Name]; ok {
+			severity = override
+		}
+		if severity == SeverityOff {
+			continue
+		}
+
+		for _, finding := range rule.Check(Template(template)) {
+			finding.Rule = rule.Name
+			finding.Severity = severity
+			findings = append(findings, finding)
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].Path != findings[j].Path {
+			return findings[i].Path < findings[j].Path
+		}
+		return findings[i].Rule < findings[j].Rule
+	})
+
+	return findings, nil
+}
+
+func isRegistered(name string) bool {
+	for _, rule := range registry {
+		if rule.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
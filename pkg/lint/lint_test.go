@@ -0,0 +1,125 @@
+package lint
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const testTemplate = `{
+	"$schema": "https://schema.management.azure.com/schemas/2019-04-01/deploymentTemplate.json#",
+	"parameters": {
+		"location": {"type": "string", "defaultValue": "[resourceGroup().location]"},
+		"unused": {"type": "string"},
+		"password": {"type": "securestring", "defaultValue": "P@ssw0rd"},
+		"generated": {"type": "securestring", "defaultValue": "[newGuid()]"}
+	},
+	"variables": {
+		"storageName": "[concat('st', uniqueString(resourceGroup().id))]",
+		"unusedVariable": "value"
+	},
+	"resources": [
+		{
+			"type": "Microsoft.Storage/storageAccounts",
+			"apiVersion": "2018-07-01",
+			"name": "[variables('storageName')]",
+			"location": "westeurope"
+		},
+		{
+			"type": "Microsoft.Network/virtualNetworks",
+			"apiVersion": "2021-02-01",
+			"name": "vnet",
+			"location": "[parameters('location')]",
+			"dependsOn": ["[resourceId('Microsoft.Web/sites', 'app')]", "[resourceId('Microsoft.Storage/storageAccounts', variables('storageName'))]"],
+			"properties": {"secret": "[parameters('password')]", "generated": "[parameters('generated')]"},
+			"resources": [
+				{"type": "subnets", "name": "default", "dependsOn": ["missing"]}
+			]
+		},
+		{
+			"type": "Microsoft.Network/dnsZones",
+			"apiVersion": "2018-05-01",
+			"name": "example.com",
+			"location": "global"
+		}
+	],
+	"outputs": {
+		"keys": {"type": "object", "value": "[listKeys(variables('storageName'), '2021-04-01')]"},
+		"password": {"type": "string", "value": "[parameters('password')]"},
+		"securePassword": {"type": "securestring", "value": "[parameters('password')]"}
+	}
+}`
+
+func readTestTemplate(t *testing.T, data string) map[string]interface{} {
+	var template map[string]interface{}
+	if err := json.Unmarshal([]byte(data), &template); err != nil {
+		t.Fatalf("Failed to parse template: %s", err)
+	}
+
+	return template
+}
+
+func formatFindings(findings []Finding) string {
+	var lines []string
+	for _, finding := range findings {
+		lines = append(lines, fmt.Sprintf("%s %s %s: %s", finding.Severity, finding.Rule, finding.Path, finding.Message))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func TestRun(t *testing.T) {
+	findings, err := Run(readTestTemplate(t, testTemplate), map[string]Severity{"unused-variable": SeverityOff, "hardcoded-location": SeverityNotice})
+	if err != nil {
+		t.Fatalf("Got unexpected error: %s", err)
+	}
+
+	want := []string{
+		"error secret-output outputs.keys: output keys exposes the result of a list function, declare it as securestring or secureobject",
+		"error secret-output outputs.password: output password exposes the secure parameter password, declare it as securestring or secureobject",
+		"error secure-parameter-default parameters.password.defaultValue: secure parameter password has a hardcoded default value",
+		"warning unused-parameter parameters.unused: parameter unused is never used",
+		"warning deprecated-api-version resources[0].apiVersion: apiVersion 2018-07-01 of Microsoft.Storage/storageAccounts is deprecated, use 2019-06-01 or newer",
+		"notice hardcoded-location resources[0].location: location westeurope of Microsoft.Storage/storageAccounts is hardcoded, use a parameter or resourceGroup().location",
+		"error invalid-depends-on resources[1].dependsOn[0]: dependsOn [resourceId('Microsoft.Web/sites', 'app')] references the type Microsoft.Web/sites, which isn't deployed by the template",
+		"error missing-api-version resources[1].resources[0]: resource Microsoft.Network/virtualNetworks/subnets has no apiVersion",
+	}
+	if got := formatFindings(findings); got != strings.Join(want, "\n") {
+		t.Errorf("Got invalid findings, expected\n%s\ngot\n%s", strings.Join(want, "\n"), got)
+	}
+}
+
+func TestRunUnknownRule(t *testing.T) {
+	if _, err := Run(map[string]interface{}{}, map[string]Severity{"no-such-rule": SeverityError}); err == nil {
+		t.Error("Got no error for an unknown rule")
+	}
+}
+
+func TestDependsOnLiteralNames(t *testing.T) {
+	template := readTestTemplate(t, `{
+		"resources": [
+			{"type": "Microsoft.Storage/storageAccounts", "apiVersion": "2021-04-01", "name": "storage", "copy": {"name": "storageLoop", "count": 2}},
+			{"type": "Microsoft.Web/sites", "apiVersion": "2021-02-01", "name": "app", "dependsOn": ["storageLoop", "storage", "Microsoft.Storage/storageAccounts/storage", "plan"]}
+		]
+	}`)
+
+	findings, err := Run(template, map[string]Severity{"hardcoded-location": SeverityOff})
+	if err != nil {
+		t.Fatalf("Got unexpected error: %s", err)
+	}
+
+	want := "error invalid-depends-on resources[1].dependsOn[3]: dependsOn plan isn't a resource of the template"
+	if got := formatFindings(findings); got != want {
+		t.Errorf("Got invalid findings, expected\n%s\ngot\n%s", want, got)
+	}
+}
+
+func TestParseSeverity(t *testing.T) {
+	if severity, err := ParseSeverity(" Warning "); err != nil || severity != SeverityWarning {
+		t.Errorf("Got invalid severity %s: %v", severity, err)
+	}
+	if _, err := ParseSeverity("fatal"); err == nil {
+		t.Error("Got no error for an invalid severity")
+	}
+}
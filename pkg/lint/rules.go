@@ -0,0 +1,282 @@
+/*
+Copyright (c) 2020 white duck Gesellschaft für Softwareentwicklung mbH
+
+This code is licensed under MIT license (see LICENSE for details)
+*/
+package lint
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	parameterReferencePattern = regexp.MustCompile(`(?i)\bparameters\(\s*'([^']+)'\s*\)`)
+	variableReferencePattern  = regexp.MustCompile(`(?i)\bvariables\(\s*'([^']+)'\s*\)`)
+	listFunctionPattern       = regexp.MustCompile(`(?i)\blist[a-z]*\(`)
+	resourceIDTypePattern     = regexp.MustCompile(`(?i)\bresourceId\(([^)]*)\)`)
+	quotedArgumentPattern     = regexp.MustCompile(`'([^']*)'`)
+)
+
+func init() {
+	Register(Rule{Name: "unused-parameter", Description: "Parameters which are never referenced", Severity: SeverityWarning, Check: checkUnusedParameters})
+	Register(Rule{Name: "unused-variable", Description: "Variables which are never referenced", Severity: SeverityWarning, Check: checkUnusedVariables})
+	Register(Rule{Name: "hardcoded-location", Description: "Resource locations which aren't an expression", Severity: SeverityWarning, Check: checkHardcodedLocations})
+	Register(Rule{Name: "missing-api-version", Description: "Resources without apiVersion", Severity: SeverityError, Check: checkMissingAPIVersions})
+	Register(Rule{Name: "deprecated-api-version", Description: "Resources with a deprecated apiVersion", Severity: SeverityWarning, Check: checkDeprecatedAPIVersions})
+	Register(Rule{Name: "secure-parameter-default", Description: "Secure parameters with a default value", Severity: SeverityError, Check: checkSecureParameterDefaults})
+	Register(Rule{Name: "secret-output", Description: "Outputs which expose secrets", Severity: SeverityError, Check: checkSecretOutputs})
+	Register(Rule{Name: "invalid-depends-on", Description: "dependsOn entries which aren't resources of the template", Severity: SeverityError, Check: checkDependsOn})
+}
+
+// references returns the lower case names referenced with the pattern anywhere in the template
+func references(template Template, pattern *regexp.Regexp) map[string]bool {
+	names := make(map[string]bool)
+	for _, value := range template.Strings() {
+		for _, match := range pattern.FindAllStringSubmatch(value, -1) {
+			names[strings.ToLower(match[1])] = true
+		}
+	}
+
+	return names
+}
+
+func checkUnusedParameters(template Template) []Finding {
+	used := references(template, parameterReferencePattern)
+
+	var findings []Finding
+	for _, name := range sortedKeys(template.Section("parameters")) {
+		if !used[strings.ToLower(name)] {
+			findings = append(findings, Finding{Path: "parameters." + name, Message: fmt.Sprintf("parameter %s is never used", name)})
+		}
+	}
+
+	return findings
+}
+
+func checkUnusedVariables(template Template) []Finding {
+	used := references(template, variableReferencePattern)
+
+	var findings []Finding
+	for _, name := range sortedKeys(template.Section("variables")) {
+		// copy declares loops of variables, it's no variable itself
+		if strings.EqualFold(name, "copy") {
+			continue
+		}
+
+		if !used[strings.ToLower(name)] {
+			findings = append(findings, Finding{Path: "variables." + name, Message: fmt.Sprintf("variable %s is never used", name)})
+		}
+	}
+
+	return findings
+}
+
+func checkHardcodedLocations(template Template) []Finding {
+	var findings []Finding
+	for _, resource := range template.Resources() {
+		location, ok := resource.Properties["location"].(string)
+		if !ok || IsExpression(location) || strings.EqualFold(location, "global") {
+			continue
+		}
+
+		findings = append(findings, Finding{
+			Path:    resource.Path + ".location",
+			Message: fmt.Sprintf("location %s of %s is hardcoded, use a parameter or resourceGroup().location", location, resource.Type),
+		})
+	}
+
+	return findings
+}
+
+func checkMissingAPIVersions(template Template) []Finding {
+	var findings []Finding
+	for _, resource := range template.Resources() {
+		if apiVersion, _ := resource.Properties["apiVersion"].(string); len(strings.TrimSpace(apiVersion)) == 0 {
+			findings = append(findings, Finding{Path: resource.Path, Message: fmt.Sprintf("resource %s has no apiVersion", resource.Type)})
+		}
+	}
+
+	return findings
+}
+
+func checkDeprecatedAPIVersions(template Template) []Finding {
+	var findings []Finding
+	for _, resource := range template.Resources() {
+		apiVersion, _ := resource.Properties["apiVersion"].(string)
+		minimum, ok := minimumAPIVersions[strings.ToLower(resource.Type)]
+		if !ok || len(apiVersion) < 10 || IsExpression(apiVersion) {
+			continue
+		}
+
+		// api versions start with the date, so they are ordered by comparing the date
+		if apiVersion[:10] < minimum[:10] {
+			findings = append(findings, Finding{
+				Path:    resource.Path + ".apiVersion",
+				Message: fmt.Sprintf("apiVersion %s of %s is deprecated, use %s or newer", apiVersion, resource.Type, minimum),
+			})
+		}
+	}
+
+	return findings
+}
+
+// secureParameters returns the lower case names of the securestring and secureobject parameters
+func secureParameters(template Template) map[string]bool {
+	secure := make(map[string]bool)
+	for name, declaration := range template.Section("parameters") {
+		declaration, _ := declaration.(map[string]interface{})
+		parameterType, _ := declaration["type"].(string)
+		if strings.EqualFold(parameterType, "securestring") || strings.EqualFold(parameterType, "secureobject") {
+			secure[strings.ToLower(name)] = true
+		}
+	}
+
+	return secure
+}
+
+func checkSecureParameterDefaults(template Template) []Finding {
+	secure := secureParameters(template)
+	parameters := template.Section("parameters")
+
+	var findings []Finding
+	for _, name := range sortedKeys(parameters) {
+		declaration, _ := parameters[name].(map[string]interface{})
+		if !secure[strings.ToLower(name)] {
+			continue
+		}
+
+		// Empty defaults and generated values like newGuid() don't leak a secret
+		hardcoded := false
+		switch value := declaration["defaultValue"].(type) {
+		case string:
+			hardcoded = len(value) > 0 && !IsExpression(value)
+		case map[string]interface{}:
+			hardcoded = len(value) > 0
+		}
+
+		if hardcoded {
+			findings = append(findings, Finding{
+				Path:    "parameters." + name + ".defaultValue",
+				Message: fmt.Sprintf("secure parameter %s has a hardcoded default value", name),
+			})
+		}
+	}
+
+	return findings
+}
+
+func checkSecretOutputs(template Template) []Finding {
+	secure := secureParameters(template)
+	outputs := template.Section("outputs")
+
+	var findings []Finding
+	for _, name := range sortedKeys(outputs) {
+		output, _ := outputs[name].(map[string]interface{})
+		outputType, _ := output["type"].(string)
+		if strings.EqualFold(outputType, "securestring") || strings.EqualFold(outputType, "secureobject") {
+			continue
+		}
+
+		var values []string
+		collectStrings(output["value"], &values)
+
+		for _, value := range values {
+			reason := ""
+			if listFunctionPattern.MatchString(value) {
+				reason = "the result of a list function"
+			}
+			for _, match := range parameterReferencePattern.FindAllStringSubmatch(value, -1) {
+				if secure[strings.ToLower(match[1])] {
+					reason = fmt.Sprintf("the secure parameter %s", match[1])
+				}
+			}
+
+			if len(reason) > 0 {
+				findings = append(findings, Finding{
+					Path:    "outputs." + name,
+					Message: fmt.Sprintf("output %s exposes %s, declare it as securestring or secureobject", name, reason),
+				})
+				break
+			}
+		}
+	}
+
+	return findings
+}
+
+func checkDependsOn(template Template) []Finding {
+	resources := template.Resources()
+
+	// Symbolic names (languageVersion 2.0) are the only valid literal dependencies
+	_, symbolic := template["resources"].(map[string]interface{})
+
+	types := make(map[string]bool)
+	names := make(map[string]bool)
+	literalNames := true
+	for _, resource := range resources {
+		types[strings.ToLower(resource.Type)] = true
+
+		name, _ := resource.Properties["name"].(string)
+		if IsExpression(name) {
+			literalNames = false
+		}
+		names[strings.ToLower(name)] = true
+		names[strings.ToLower(resource.Type+"/"+name)] = true
+
+		if loop, ok := resource.Properties["copy"].(map[string]interface{}); ok {
+			if loopName, ok := loop["name"].(string); ok {
+				names[strings.ToLower(loopName)] = true
+			}
+		}
+	}
+	if symbolic {
+		names = make(map[string]bool)
+		for _, name := range sortedKeys(template.Section("resources")) {
+			names[strings.ToLower(name)] = true
+		}
+	}
+
+	var findings []Finding
+	for _, resource := range resources {
+		dependsOn, _ := resource.Properties["dependsOn"].([]interface{})
+		for i, entry := range dependsOn {
+			dependency, ok := entry.(string)
+			if !ok {
+				continue
+			}
+
+			path := fmt.Sprintf("%s.dependsOn[%d]", resource.Path, i)
+			if IsExpression(dependency) {
+				if dependencyType, ok := resourceIDType(dependency); ok && !types[strings.ToLower(dependencyType)] {
+					findings = append(findings, Finding{Path: path, Message: fmt.Sprintf("dependsOn %s references the type %s, which isn't deployed by the template", dependency, dependencyType)})
+				}
+				continue
+			}
+
+			// Literal names can't be checked if names of resources are expressions
+			if (symbolic || literalNames) && !names[strings.ToLower(dependency)] {
+				findings = append(findings, Finding{Path: path, Message: fmt.Sprintf("dependsOn %s isn't a resource of the template", dependency)})
+			}
+		}
+	}
+
+	return findings
+}
+
+// resourceIDType returns the resource type of a resourceId() expression, which is the first quoted argument containing a /
+func resourceIDType(expression string) (string, bool) {
+	match := resourceIDTypePattern.FindStringSubmatch(expression)
+	if match == nil {
+		return "", false
+	}
+
+	for _, argument := range quotedArgumentPattern.FindAllStringSubmatch(match[1], -1) {
+		if strings.Contains(argument[1], "/") {
+			return argument[1], true
+		}
+	}
+
+	return "", false
+}
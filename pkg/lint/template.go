@@ -0,0 +1,103 @@
+/*
+Copyright (c) 2020 white duck Gesellschaft für Softwareentwicklung mbH
+
+This code is licensed under MIT license (see LICENSE for details)
+*/
+package lint
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Template is a parsed arm template with helpers for the rules
+type Template map[string]interface{}
+
+// Resource is a resource of the template with its json path, nested resources are included.
+// The type of nested resources is prefixed with the type of their parent.
+type Resource struct {
+	Path       string
+	Type       string
+	Properties map[string]interface{}
+}
+
+// Section returns a top level object like parameters, variables or outputs
+func (template Template) Section(name string) map[string]interface{} {
+	section, _ := template[name].(map[string]interface{})
+	return section
+}
+
+// Resources returns all resources including nested ones. Templates with symbolic
+// names (languageVersion 2.0) declare the resources as object instead of array.
+func (template Template) Resources() []Resource {
+	var resources []Resource
+	collectResources(template["resources"], "resources", "", &resources)
+	return resources
+}
+
+func collectResources(v interface{}, path, parentType string, resources *[]Resource) {
+	add := func(path string, value interface{}) {
+		resource, ok := value.(map[string]interface{})
+		if !ok {
+			return
+		}
+
+		resourceType, _ := resource["type"].(string)
+		if len(parentType) > 0 && !strings.Contains(resourceType, "/") {
+			resourceType = fmt.Sprintf("%s/%s", parentType, resourceType)
+		}
+
+		*resources = append(*resources, Resource{Path: path, Type: resourceType, Properties: resource})
+		collectResources(resource["resources"], path+".resources", resourceType, resources)
+	}
+
+	switch v := v.(type) {
+	case []interface{}:
+		for i, value := range v {
+			add(fmt.Sprintf("%s[%d]", path, i), value)
+		}
+	case map[string]interface{}:
+		for _, name := range sortedKeys(v) {
+			add(fmt.Sprintf("%s.%s", path, name), v[name])
+		}
+	}
+}
+
+// Strings returns every string value of the template, including nested ones
+func (template Template) Strings() []string {
+	var values []string
+	collectStrings(map[string]interface{}(template), &values)
+	return values
+}
+
+func collectStrings(v interface{}, values *[]string) {
+	switch v := v.(type) {
+	case string:
+		*values = append(*values, v)
+	case []interface{}:
+		for _, value := range v {
+			collectStrings(value, values)
+		}
+	case map[string]interface{}:
+		for _, value := range v {
+			collectStrings(value, values)
+		}
+	}
+}
+
+// IsExpression checks if the value is a template expression, [[ escapes a literal [
+func IsExpression(value string) bool {
+	value = strings.TrimSpace(value)
+	return strings.HasPrefix(value, "[") && !strings.HasPrefix(value, "[[") && strings.HasSuffix(value, "]")
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,118 @@
+/*
+Copyright (c) 2020 white duck Gesellschaft für Softwareentwicklung mbH
+
+This code is licensed under MIT license (see LICENSE for details)
+*/
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// IsBicepFile checks if the path points to a bicep file
+func IsBicepFile(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".bicep")
+}
+
+// IsBicepParamFile checks if the path points to a bicep parameters file
+func IsBicepParamFile(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".bicepparam")
+}
+
+// GetBicepCommand can be used to run arbitrary bicep commands
+func GetBicepCommand() (*exec.Cmd, error) {
+	// This is the path that a developer can set to tell us what the install path for bicep is.
+	const bicepPath = "BicepPath"
+
+	// The default install paths are used to find bicep. This is for security, so that any path in the calling program's Path environment is not used to execute bicep.
+	bicepDefaultPathWindows := []string{
+		fmt.Sprintf("%s\\Bicep CLI", os.Getenv("ProgramFiles")),
+		fmt.Sprintf("%s\\.azure\\bin", os.Getenv("USERPROFILE")),
+	}
+
+	// Default paths for non-Windows.
+	bicepDefaultPath := []string{"/bin", "/sbin", "/usr/bin", "/usr/local/bin", fmt.Sprintf("%s/.azure/bin", os.Getenv("HOME"))}
+
+	binary := "bicep"
+	paths := bicepDefaultPath
+	separator := ":"
+	if runtime.GOOS == "windows" {
+		binary = "bicep.exe"
+		paths = bicepDefaultPathWindows
+		separator = ";"
+	}
+
+	if customPath, ok := os.LookupEnv(bicepPath); ok && len(customPath) > 0 {
+		paths = append(strings.Split(customPath, separator), paths...)
+	}
+
+	for _, dir := range paths {
+		candidate := filepath.Join(strings.TrimSpace(dir), binary)
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			return exec.Command(candidate), nil
+		}
+	}
+
+	return nil, fmt.Errorf("failed to find the bicep binary, set %s to the directory containing it", bicepPath)
+}
+
+// ReadBicep compiles a bicep file to an arm template and unmashals it.
+func ReadBicep(path string) (map[string]interface{}, error) {
+	cmd, err := GetBicepCommand()
+	if err != nil {
+		return nil, err
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd.Args = append(cmd.Args, "build", path, "--stdout")
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("failed to compile bicep file %s: %v, %s", path, err, strings.TrimSpace(stderr.String()))
+	}
+
+	contents := make(map[string]interface{})
+	if err := json.Unmarshal(stdout.Bytes(), &contents); err != nil {
+		return nil, fmt.Errorf("failed to parse compiled bicep file %s: %v", path, err)
+	}
+
+	return contents, nil
+}
+
+// ReadBicepParams compiles a bicep parameters file to an arm parameters file and unmashals it.
+func ReadBicepParams(path string) (map[string]interface{}, error) {
+	cmd, err := GetBicepCommand()
+	if err != nil {
+		return nil, err
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd.Args = append(cmd.Args, "build-params", path, "--stdout")
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("failed to compile bicep parameters file %s: %v, %s", path, err, strings.TrimSpace(stderr.String()))
+	}
+
+	contents := make(map[string]interface{})
+	if err := json.Unmarshal(stdout.Bytes(), &contents); err != nil {
+		return nil, fmt.Errorf("failed to parse compiled bicep parameters file %s: %v", path, err)
+	}
+
+	// Newer bicep versions print the parameters file wrapped as string next to the template
+	if parametersJSON, ok := contents["parametersJson"].(string); ok {
+		contents = make(map[string]interface{})
+		if err := json.Unmarshal([]byte(parametersJSON), &contents); err != nil {
+			return nil, fmt.Errorf("failed to parse compiled bicep parameters file %s: %v", path, err)
+		}
+	}
+
+	return contents, nil
+}
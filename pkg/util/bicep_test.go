@@ -0,0 +1,87 @@
+package util
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// fakeBicep is a stand-in for the bicep cli which answers build and build-params
+const fakeBicep = `#!/bin/sh
+case "$1" in
+build)
+	case "$2" in
+	*broken.bicep) echo "broken.bicep(1,1) : Error BCP007: This declaration type is not recognized." >&2; exit 1 ;;
+	esac
+	echo '{"$schema":"https://schema.management.azure.com/schemas/2019-04-01/deploymentTemplate.json#","resources":[]}'
+	;;
+build-params)
+	echo '{"parametersJson":"{\"parameters\":{\"capacity\":{\"value\":2}}}","templateJson":"{}"}'
+	;;
+esac
+`
+
+// useFakeBicep installs the fake bicep cli and points BicepPath to it
+func useFakeBicep(t *testing.T) string {
+	if runtime.GOOS == "windows" {
+		t.Skip("The fake bicep cli is a shell script")
+	}
+
+	dir := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(dir, "bicep"), []byte(fakeBicep), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	os.Setenv("BicepPath", dir)
+	t.Cleanup(func() { os.Unsetenv("BicepPath") })
+	return dir
+}
+
+func TestGetBicepCommand(t *testing.T) {
+	dir := useFakeBicep(t)
+
+	cmd, err := GetBicepCommand()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(dir, "bicep"); cmd.Path != want {
+		t.Errorf("Got invalid bicep path, expected %s got %s", want, cmd.Path)
+	}
+}
+
+func TestReadBicep(t *testing.T) {
+	useFakeBicep(t)
+
+	template, err := ReadBicep("main.bicep")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resources, ok := template["resources"].([]interface{}); !ok || len(resources) != 0 {
+		t.Errorf("Got invalid template, expected empty resources got %v", template)
+	}
+
+	_, err = ReadBicep("broken.bicep")
+	if err == nil || !strings.Contains(err.Error(), "BCP007") {
+		t.Errorf("Got invalid error, expected the bicep diagnostics got %v", err)
+	}
+}
+
+func TestReadBicepParams(t *testing.T) {
+	useFakeBicep(t)
+
+	parameters, err := ReadBicepParams("main.bicepparam")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]interface{}{
+		"parameters": map[string]interface{}{"capacity": map[string]interface{}{"value": float64(2)}},
+	}
+	if !reflect.DeepEqual(parameters, want) {
+		t.Errorf("Got invalid parameters, expected %v got %v", want, parameters)
+	}
+}
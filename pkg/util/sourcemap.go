@@ -0,0 +1,242 @@
+/*
+Copyright (c) 2020 white duck Gesellschaft für Softwareentwicklung mbH
+
+This code is licensed under MIT license (see LICENSE for details)
+*/
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// SourceMap maps the json paths of a file to the line they are defined on
+type SourceMap struct {
+	File  string
+	lines map[string]int
+	// positions are the offsets of the json paths in the compact template, which arm reports positions in
+	positions []pathOffset
+}
+
+type pathOffset struct {
+	path   string
+	offset int
+}
+
+// templatePathPattern matches json paths of template sections in arm error messages, e.g. resources[3].properties.sku
+var templatePathPattern = regexp.MustCompile(`(?i)\b(?:parameters|variables|resources|outputs|functions)(?:\[\d+\]|\['[^']*'\]|\.[\w$@-]+)+`)
+
+// positionPattern matches positions in arm error messages, e.g. "at line '1' and column '1234'" or "line 1, position 1234"
+var positionPattern = regexp.MustCompile(`(?i)\bline:?\s*'?(\d+)'?,?\s*(?:and\s+)?(?:column|position):?\s*'?(\d+)'?`)
+
+// bracketKeyPattern matches quoted keys like ['sku'], which are normalized to .sku
+var bracketKeyPattern = regexp.MustCompile(`\['([^']*)'\]|\["([^"]*)"\]`)
+
+// NewSourceMap builds the source map of the json document
+func NewSourceMap(file string, data []byte) (SourceMap, error) {
+	var contents interface{}
+	if err := json.Unmarshal(data, &contents); err != nil {
+		return SourceMap{}, fmt.Errorf("failed to build source map of %s: %v", file, err)
+	}
+
+	return newSourceMap(file, data, contents)
+}
+
+// newSourceMap builds the source map of the json document, contents is the decoded document
+func newSourceMap(file string, data []byte, contents interface{}) (SourceMap, error) {
+	builder := sourceMapBuilder{
+		decoder:   json.NewDecoder(bytes.NewReader(data)),
+		sourceMap: SourceMap{File: file, lines: make(map[string]int)},
+	}
+
+	builder.lineStarts = append(builder.lineStarts, 0)
+	for i, b := range data {
+		if b == '\n' {
+			builder.lineStarts = append(builder.lineStarts, i+1)
+		}
+	}
+
+	if err := builder.value("", true); err != nil {
+		return SourceMap{}, fmt.Errorf("failed to build source map of %s: %v", file, err)
+	}
+
+	// The template is sent compact, with the keys sorted by json.Marshal
+	compact, err := json.Marshal(contents)
+	if err != nil {
+		return SourceMap{}, fmt.Errorf("failed to build source map of %s: %v", file, err)
+	}
+
+	compactBuilder := sourceMapBuilder{
+		decoder:   json.NewDecoder(bytes.NewReader(compact)),
+		sourceMap: SourceMap{lines: make(map[string]int)},
+		positions: &builder.sourceMap.positions,
+	}
+	if err := compactBuilder.value("", true); err != nil {
+		return SourceMap{}, fmt.Errorf("failed to build source map of %s: %v", file, err)
+	}
+
+	// arm counts characters, the decoder bytes
+	characters, previous := 0, 0
+	for i, position := range builder.sourceMap.positions {
+		characters += utf8.RuneCount(compact[previous:position.offset])
+		previous = position.offset
+		builder.sourceMap.positions[i].offset = characters
+	}
+
+	return builder.sourceMap, nil
+}
+
+// Line returns the line of the json path, if the path itself is unknown
+// the line of the closest known parent is used
+func (sourceMap SourceMap) Line(path string) (int, bool) {
+	path = normalizePath(path)
+	for len(path) > 0 {
+		if line, ok := sourceMap.lines[path]; ok {
+			return line, true
+		}
+
+		path = parentPath(path)
+	}
+
+	return 0, false
+}
+
+// Locate searches the texts for template json paths and returns the line of the first known one.
+// If there is none, positions like "line '1' and column '1234'" in the compact template are used.
+func (sourceMap SourceMap) Locate(texts ...string) (int, bool) {
+	if len(sourceMap.lines) == 0 {
+		return 0, false
+	}
+
+	for _, text := range texts {
+		for _, path := range templatePathPattern.FindAllString(text, -1) {
+			if line, ok := sourceMap.Line(path); ok {
+				return line, true
+			}
+		}
+	}
+
+	for _, text := range texts {
+		for _, match := range positionPattern.FindAllStringSubmatch(text, -1) {
+			line, _ := strconv.Atoi(match[1])
+			column, _ := strconv.Atoi(match[2])
+			if path, ok := sourceMap.pathAt(line, column); ok {
+				return sourceMap.Line(path)
+			}
+		}
+	}
+
+	return 0, false
+}
+
+// pathAt returns the json path at the position of the compact template, which is the last path starting before it.
+// The compact template is a single line, the column counts characters.
+func (sourceMap SourceMap) pathAt(line, column int) (string, bool) {
+	if line != 1 || column <= 0 {
+		return "", false
+	}
+
+	path, found := "", false
+	for _, position := range sourceMap.positions {
+		if position.offset > column {
+			break
+		}
+		path, found = position.path, true
+	}
+
+	return path, found
+}
+
+type sourceMapBuilder struct {
+	decoder    *json.Decoder
+	lineStarts []int
+	sourceMap  SourceMap
+	// positions records the offsets of the paths instead of their lines, if set
+	positions *[]pathOffset
+}
+
+// value reads the next json value and records the lines of it and all its children,
+// members of objects are recorded on the line of their key instead of their value
+func (builder *sourceMapBuilder) value(path string, record bool) error {
+	token, err := builder.decoder.Token()
+	if err != nil {
+		return err
+	}
+
+	if record && len(path) > 0 {
+		builder.record(path)
+	}
+
+	switch token {
+	case json.Delim('{'):
+		for builder.decoder.More() {
+			key, err := builder.decoder.Token()
+			if err != nil {
+				return err
+			}
+
+			child := strings.ToLower(fmt.Sprint(key))
+			if len(path) > 0 {
+				child = fmt.Sprintf("%s.%s", path, child)
+			}
+			builder.record(child)
+
+			if err := builder.value(child, false); err != nil {
+				return err
+			}
+		}
+	case json.Delim('['):
+		for i := 0; builder.decoder.More(); i++ {
+			if err := builder.value(fmt.Sprintf("%s[%d]", path, i), true); err != nil {
+				return err
+			}
+		}
+	default:
+		return nil
+	}
+
+	// Consume the closing delimiter
+	_, err = builder.decoder.Token()
+	return err
+}
+
+// record records the line or the offset of the path at the last read token
+func (builder *sourceMapBuilder) record(path string) {
+	if builder.positions != nil {
+		*builder.positions = append(*builder.positions, pathOffset{path: path, offset: int(builder.decoder.InputOffset())})
+		return
+	}
+
+	builder.sourceMap.lines[path] = builder.line()
+}
+
+// line returns the line of the last read token, tokens never span multiple lines
+func (builder *sourceMapBuilder) line() int {
+	offset := int(builder.decoder.InputOffset())
+	return sort.Search(len(builder.lineStarts), func(i int) bool {
+		return builder.lineStarts[i] >= offset
+	})
+}
+
+// normalizePath converts the path to the format of the source map,
+// arm prefixes the paths with the deployment properties sometimes
+func normalizePath(path string) string {
+	path = bracketKeyPattern.ReplaceAllString(strings.TrimSpace(path), ".$1$2")
+	path = strings.ToLower(strings.TrimPrefix(path, "."))
+	path = strings.TrimPrefix(path, "properties.")
+	return strings.TrimPrefix(path, "template.")
+}
+
+func parentPath(path string) string {
+	if i := strings.LastIndexAny(path, ".["); i >= 0 {
+		return path[:i]
+	}
+
+	return ""
+}
@@ -0,0 +1,98 @@
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const sourceMapTemplate = `{
+  "$schema": "https://schema.management.azure.com/schemas/2019-04-01/deploymentTemplate.json#",
+  "parameters": {
+    "name": { "type": "string" }
+  },
+  "resources": [
+    {
+      "type": "Microsoft.Storage/storageAccounts",
+      "name": "[parameters('name')]",
+      "sku": {
+        "name": "Standard_LRS"
+      },
+      "properties": {
+        "accessTier": "Hot"
+      }
+    }
+  ]
+}`
+
+func TestSourceMapLine(t *testing.T) {
+	sourceMap, err := NewSourceMap("template.json", []byte(sourceMapTemplate))
+	if err != nil {
+		t.Fatalf("Got unexpected error: %s", err)
+	}
+
+	tests := []struct {
+		path string
+		want int
+	}{
+		{path: "parameters.name", want: 4},
+		{path: "resources[0]", want: 7},
+		{path: "resources[0].sku.name", want: 11},
+		{path: "resources[0].properties['accessTier']", want: 14},
+		{path: "properties.template.resources[0].SKU", want: 10},
+		{path: "resources[0].properties.missing", want: 13},
+	}
+
+	for _, test := range tests {
+		if line, ok := sourceMap.Line(test.path); !ok || line != test.want {
+			t.Errorf("Got invalid line for %s, expected %d got %d", test.path, test.want, line)
+		}
+	}
+
+	if line, ok := sourceMap.Locate("", "The template resource 'resources[0].sku' is not valid"); !ok || line != 10 {
+		t.Errorf("Got invalid line for error message, expected 10 got %d", line)
+	}
+
+	// arm reports positions in the compact template, which has sorted keys
+	var contents interface{}
+	json.Unmarshal([]byte(sourceMapTemplate), &contents)
+	compact, _ := json.Marshal(contents)
+	column := strings.Index(string(compact), `"accessTier"`) + len(`"accessTier":`)
+	message := fmt.Sprintf("Unable to parse the template at line '1' and column '%d'", column)
+	if line, ok := sourceMap.Locate(message); !ok || line != 14 {
+		t.Errorf("Got invalid line for %s, expected 14 got %d", message, line)
+	}
+
+	if _, ok := sourceMap.Locate("Unable to parse the template at line '3' and column '1'"); ok {
+		t.Errorf("Got line for a position outside of the compact template")
+	}
+
+	if _, ok := sourceMap.Locate("The template is not valid"); ok {
+		t.Errorf("Got line for error message without json path")
+	}
+}
+
+func TestReadJSONWithSourceMap(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "template.json")
+	if err := ioutil.WriteFile(path, []byte(sourceMapTemplate), 0644); err != nil {
+		t.Fatalf("Got unexpected error: %s", err)
+	}
+
+	contents, sourceMap, err := ReadJSONWithSourceMap(path)
+	if err != nil {
+		t.Fatalf("Got unexpected error: %s", err)
+	}
+
+	if _, ok := contents["resources"].([]interface{}); !ok {
+		t.Errorf("Got invalid template %v", contents)
+	}
+	if sourceMap.File != path {
+		t.Errorf("Got invalid source map file, expected %s got %s", path, sourceMap.File)
+	}
+	if line, ok := sourceMap.Line("resources[0].sku"); !ok || line != 10 {
+		t.Errorf("Got invalid line for resources[0].sku, expected 10 got %d", line)
+	}
+}
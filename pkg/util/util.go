@@ -7,6 +7,7 @@ package util
 
 import (
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
 
 	"github.com/sirupsen/logrus"
@@ -26,24 +27,111 @@ func ReadJSON(path string) (map[string]interface{}, error) {
 	return contents, nil
 }
 
-// MergeParameters takes the original and override parameters and merges them together
-func MergeParameters(original map[string]interface{}, override map[string]interface{}) map[string]interface{} {
-	switch {
-	case original == nil && override == nil:
-		return make(map[string]interface{})
-	case original == nil:
-		return override
-	case override == nil:
-		return original
-	case len(override) == 0:
-		return original
-	default:
-		break
+// ReadJSONWithSourceMap reads a json file like ReadJSON and builds its source map from the same read
+func ReadJSONWithSourceMap(path string) (map[string]interface{}, SourceMap, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, SourceMap{}, fmt.Errorf("failed to read template file: %v", err)
+	}
+	contents := make(map[string]interface{})
+	if err := json.Unmarshal(data, &contents); err != nil {
+		return nil, SourceMap{}, err
+	}
+
+	sourceMap, err := newSourceMap(path, data, contents)
+	if err != nil {
+		return nil, SourceMap{}, err
+	}
+	return contents, sourceMap, nil
+}
+
+// ParameterLayer is a source of parameters, which is layered on the previous sources
+type ParameterLayer struct {
+	Name       string
+	Parameters map[string]interface{}
+	// DeepMerge merges object values with the value of the previous layers instead of replacing them
+	DeepMerge bool
+}
+
+// MergeParameterLayers merges the layers in order, every layer replaces or deep merges the parameters of the previous ones.
+// The layers are copied, so they aren't modified. The provenance lists the names of the layers which supplied each parameter.
+func MergeParameterLayers(layers ...ParameterLayer) (map[string]interface{}, map[string][]string) {
+	merged := make(map[string]interface{})
+	provenance := make(map[string][]string)
+	for _, layer := range layers {
+		for name, value := range layer.Parameters {
+			value = copyValue(value)
+			if previous, ok := merged[name]; ok && layer.DeepMerge {
+				if deep, ok := mergeParameterValue(previous, value); ok {
+					merged[name] = deep
+					provenance[name] = append(provenance[name], layer.Name)
+					continue
+				}
+			}
+
+			merged[name] = value
+			provenance[name] = []string{layer.Name}
+		}
 	}
 
+	return merged, provenance
+}
+
+// mergeParameterValue deep merges two parameter values of the form {"value": ...}, it fails if any of them isn't an object
+func mergeParameterValue(previous, next interface{}) (interface{}, bool) {
+	previousParameter, ok := previous.(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	nextParameter, ok := next.(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+
+	previousValue, ok := previousParameter["value"].(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	nextValue, ok := nextParameter["value"].(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+
+	return map[string]interface{}{"value": deepMerge(previousValue, nextValue)}, true
+}
+
+// deepMerge merges the objects recursively, all other values including arrays are replaced
+func deepMerge(base, override map[string]interface{}) map[string]interface{} {
 	for key, value := range override {
-		original[key] = value
+		baseObject, baseOk := base[key].(map[string]interface{})
+		object, ok := value.(map[string]interface{})
+		if baseOk && ok {
+			base[key] = deepMerge(baseObject, object)
+			continue
+		}
+
+		base[key] = value
 	}
 
-	return original
+	return base
+}
+
+// copyValue copies the nested maps and slices of a decoded json value
+func copyValue(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(value))
+		for key, nested := range value {
+			copied[key] = copyValue(nested)
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(value))
+		for i, nested := range value {
+			copied[i] = copyValue(nested)
+		}
+		return copied
+	default:
+		return value
+	}
 }
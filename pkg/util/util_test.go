@@ -0,0 +1,60 @@
+package util
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestMergeParameterLayers(t *testing.T) {
+	base := map[string]interface{}{
+		"location": map[string]interface{}{"value": "westeurope"},
+		"tags":     map[string]interface{}{"value": map[string]interface{}{"owner": "team", "cost": map[string]interface{}{"center": "1"}}},
+		"ranges":   map[string]interface{}{"value": []interface{}{"10.0.0.0/24"}},
+	}
+	region := map[string]interface{}{
+		"tags":   map[string]interface{}{"value": map[string]interface{}{"region": "weu", "cost": map[string]interface{}{"project": "2"}}},
+		"ranges": map[string]interface{}{"value": []interface{}{"10.1.0.0/24"}},
+	}
+	env := map[string]interface{}{
+		"location": map[string]interface{}{"value": "northeurope"},
+		"tags":     map[string]interface{}{"value": map[string]interface{}{"env": "prod"}},
+	}
+	before, _ := json.Marshal([]interface{}{base, region, env})
+
+	merged, provenance := MergeParameterLayers(
+		ParameterLayer{Name: "base.json", Parameters: base},
+		ParameterLayer{Name: "weu.yaml", Parameters: region, DeepMerge: true},
+		ParameterLayer{Name: "prod.yaml", Parameters: env},
+	)
+
+	got, _ := json.Marshal(merged)
+	want := `{"location":{"value":"northeurope"},"ranges":{"value":["10.1.0.0/24"]},"tags":{"value":{"env":"prod"}}}`
+	if string(got) != want {
+		t.Errorf("Got invalid parameters, expected %s got %s", want, got)
+	}
+
+	wantProvenance := map[string][]string{"location": {"prod.yaml"}, "ranges": {"weu.yaml"}, "tags": {"prod.yaml"}}
+	if !reflect.DeepEqual(provenance, wantProvenance) {
+		t.Errorf("Got invalid provenance, expected %v got %v", wantProvenance, provenance)
+	}
+
+	// A deep merging last layer keeps the keys of the previous layers
+	merged, provenance = MergeParameterLayers(
+		ParameterLayer{Name: "base.json", Parameters: base},
+		ParameterLayer{Name: "weu.yaml", Parameters: region, DeepMerge: true},
+	)
+	got, _ = json.Marshal(merged["tags"])
+	want = `{"value":{"cost":{"center":"1","project":"2"},"owner":"team","region":"weu"}}`
+	if string(got) != want {
+		t.Errorf("Got invalid deep merged tags, expected %s got %s", want, got)
+	}
+	if !reflect.DeepEqual(provenance["tags"], []string{"base.json", "weu.yaml"}) {
+		t.Errorf("Got invalid provenance of the deep merged tags: %v", provenance["tags"])
+	}
+
+	after, _ := json.Marshal([]interface{}{base, region, env})
+	if string(before) != string(after) {
+		t.Errorf("Merging modified the layers:\n%s\n%s", before, after)
+	}
+}